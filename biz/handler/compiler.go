@@ -5,24 +5,9 @@ import (
 	"github.com/qimengxingyuan/young_engine/executor"
 )
 
-func Compiler(exp string) (*executor.Node, error) {
-	tokenScanner := compiler.NewScanner(exp)
-	tokens, err := tokenScanner.Lexer()
-	if err != nil {
-		return nil, err
-	}
+// ruleEngine compiles the rules of the service, the domain functions available to the rules are registered into it.
+var ruleEngine = compiler.NewEngine()
 
-	parser := compiler.NewParser(tokens)
-	err = parser.ParseSyntax()
-	if err != nil {
-		return nil, err
-	}
-
-	astBuilder := compiler.NewBuilder(parser)
-	ast, err := astBuilder.Build()
-	if err != nil {
-		return nil, err
-	}
-
-	return ast, nil
+func Compiler(exp string) (*executor.Program, error) {
+	return ruleEngine.Compile(exp)
 }
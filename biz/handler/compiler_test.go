@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"github.com/qimengxingyuan/young_engine/executor"
 	"os/exec"
 	"testing"
 	"time"
@@ -13,24 +14,24 @@ func TestCompiler(t *testing.T) {
 	//rule := `--7  * -9 + -8 * 9`
 	//rule := "s1 != 'abc123' && s2 != 'abc\n123'"
 	//rule := "\"abc\n1234\"== 'abc\n123'"
-	node, err := Compiler(rule)
+	program, err := Compiler(rule)
 	if err != nil {
 		t.Error(err)
 		return
 	}
 
 	// print and open svg
-	node.PrintSvg("node")
+	program.PrintSvg("node")
 	exec.Command("cmd", "/c", "start", "node.svg").Start()
 	exec.Command("open", "node.svg").Start()
 	time.Sleep(1 * time.Second)
 
 	// eval
 	params := map[string]interface{}{}
-	err = node.Eval(params)
+	val, tp, err := program.Eval(executor.MapParameters(params))
 	if err != nil {
 		t.Error(err)
 	} else {
-		t.Log(node.GetVal())
+		t.Log(val, tp)
 	}
 }
@@ -1,46 +1,89 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/qimengxingyuan/young_engine/compiler"
+	"github.com/qimengxingyuan/young_engine/executor"
 	"strings"
+	"time"
 )
 
 type RuleRunRequest struct {
-	Exp    string                 `json:"exp"`
-	Params map[string]interface{} `json:"params"`
+	Exp string `json:"exp"`
+	// Params is kept raw so that the numbers are decoded by getParams without going through float64
+	Params json.RawMessage `json:"params"`
+	// Missing is the policy for the parameters which are not in Params: "strict" (default), "null" or "default"
+	Missing string `json:"missing"`
+	// Decimal decodes the numbers of Params with a fraction or an exponent into decimals rather than floats
+	Decimal bool `json:"decimal"`
+	// Rounding is the rounding mode of the decimals: "half_up" (default), "half_even", "half_down", "up", "down",
+	// "ceiling" or "floor"
+	Rounding string `json:"rounding"`
 }
 
-func getParams(param map[string]interface{}) (map[string]interface{}, error) {
+func getParams(param json.RawMessage, decimal bool) (map[string]interface{}, error) {
 	newParams := make(map[string]interface{})
+	if len(param) == 0 {
+		return newParams, nil
+	}
 
-	paramsByte, _ := json.Marshal(param)
-	decoder := json.NewDecoder(strings.NewReader(string(paramsByte)))
+	decoder := json.NewDecoder(bytes.NewReader(param))
 	decoder.UseNumber()
 	if err := decoder.Decode(&newParams); err != nil {
 		return nil, fmt.Errorf("invalid request params: %v", err)
 	}
 	for k, v := range newParams {
+		val, err := normalizeNumber(v, decimal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request params: %v", err)
+		}
+		newParams[k] = val
+	}
+
+	return newParams, nil
+}
+
+// normalizeNumber converts the json.Number decoded from the request params into int64 or float64, or into
+// executor.Decimal instead of float64 when decimal is set, the elements of arrays and the members of nested
+// objects included. The integers beyond int64, such as the uint64 ids, are always decoded into decimals so that
+// they are exact.
+func normalizeNumber(v interface{}, decimal bool) (interface{}, error) {
+	switch val := v.(type) {
+	case json.Number:
 		// 用最优的方式断定类型。
-		if num, ok := v.(json.Number); ok {
-			var err error
-			if strings.Contains(num.String(), ".") || strings.Contains(num.String(), "e") {
-				newParams[k], err = num.Float64()
-			} else {
-				newParams[k], err = num.Int64()
-				if err != nil {
-					newParams[k], err = num.Float64()
-				}
+		if !strings.ContainsAny(val.String(), ".eE") {
+			if num, err := val.Int64(); err == nil {
+				return num, nil
 			}
+			return executor.ParseDecimal(val.String())
+		}
+		if decimal {
+			return executor.ParseDecimal(val.String())
+		}
+		return val.Float64()
+	case []interface{}:
+		for i, elem := range val {
+			num, err := normalizeNumber(elem, decimal)
 			if err != nil {
-				return nil, fmt.Errorf("invalid request params: %v", err)
+				return nil, err
 			}
+			val[i] = num
+		}
+	case map[string]interface{}:
+		for k, member := range val {
+			num, err := normalizeNumber(member, decimal)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = num
 		}
 	}
-
-	return newParams, nil
+	return v, nil
 }
 
 func HandleRunRule(ctx context.Context, c *app.RequestContext) {
@@ -50,20 +93,79 @@ func HandleRunRule(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
+	policy, err := executor.ParseMissingPolicy(req.Missing)
+	if err != nil {
+		BindResp(c, ParamErrCode, err.Error(), nil)
+		return
+	}
+
+	rounding, err := executor.ParseRoundingMode(req.Rounding)
+	if err != nil {
+		BindResp(c, ParamErrCode, err.Error(), nil)
+		return
+	}
+
+	params, err := getParams(req.Params, req.Decimal)
+	if err != nil {
+		BindResp(c, ParamErrCode, err.Error(), nil)
+		return
+	}
+
 	evaluatedExp, err := Compiler(req.Exp)
 	if err != nil {
+		// the data locates the errors in the expression, so that the rule editor can underline them
+		var compileErrs compiler.ErrorList
+		if errors.As(err, &compileErrs) {
+			BindResp(c, CompileErrCode, err.Error(), compileErrs)
+			return
+		}
 		BindResp(c, CompileErrCode, err.Error(), nil)
 		return
 	}
 
-	params, _ := getParams(req.Params)
-	err = evaluatedExp.Eval(params)
+	program := evaluatedExp.WithMissingPolicy(policy).WithRounding(rounding)
+	result, err := program.Evaluate(executor.MapParameters(params))
+	missing := result.Missing
+	if missing == nil {
+		missing = []string{}
+	}
 	if err != nil {
-		BindResp(c, RuleExecErrCode, err.Error(), nil)
+		// the data locates the failing sub-expression
+		data := RuleExecErrData{Message: err.Error(), Missing: missing}
+		var evalErr *executor.EvalError
+		if errors.As(err, &evalErr) {
+			data.Message, data.Span, data.Text = evalErr.Err.Error(), evalErr.Span, evalErr.Text
+		}
+		BindResp(c, RuleExecErrCode, err.Error(), data)
 		return
 	}
 
-	resp, _ := evaluatedExp.GetVal()
+	BindResp(c, SuccessCode, SuccessMsg, RuleRunData{Value: respValue(result), Missing: missing})
+}
 
-	BindResp(c, SuccessCode, SuccessMsg, resp)
+// RuleRunData is the data of the response of a successful evaluation.
+type RuleRunData struct {
+	Value interface{} `json:"value"`
+	// Missing lists the parameters of the expression which are not in the params of the request, they evaluate
+	// according to the missing policy
+	Missing []string `json:"missing"`
+}
+
+// RuleExecErrData is the data of the response of a failed evaluation, it locates the failing sub-expression like
+// executor.EvalError when the location is known.
+type RuleExecErrData struct {
+	Message string `json:"message"`
+	executor.Span
+	Text    string   `json:"text"`
+	Missing []string `json:"missing"`
+}
+
+// respValue returns the value of the result as it is rendered in the JSON response. Times are rendered in
+// RFC 3339 by encoding/json, durations are rendered like "1h30m0s" rather than as a number of nanoseconds, and
+// decimals as JSON numbers with all of their digits.
+func respValue(result executor.Result) interface{} {
+	if d, ok := result.Value.(time.Duration); ok {
+		return d.String()
+	}
+	return result.Value
 }
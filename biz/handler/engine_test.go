@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+func TestHandleRunRule_MissingParameters(t *testing.T) {
+	cases := []struct {
+		body     string
+		wantCode int
+		want     []string
+	}{
+		{body: `{"exp": "score > 90 && city == \"bj\"", "params": {"city": "bj"}}`, wantCode: RuleExecErrCode,
+			want: []string{"score"}},
+		{body: `{"exp": "score > 90 || level > 1", "missing": "null"}`, wantCode: SuccessCode,
+			want: []string{"score", "level"}},
+		{body: `{"exp": "score > 90", "params": {"score": 95}}`, wantCode: SuccessCode, want: []string{}},
+	}
+
+	for _, c := range cases {
+		ctx := app.NewContext(0)
+		ctx.Request.SetMethod("POST")
+		ctx.Request.Header.SetContentTypeBytes([]byte("application/json"))
+		ctx.Request.SetBody([]byte(c.body))
+
+		HandleRunRule(context.Background(), ctx)
+
+		var resp struct {
+			Code int `json:"code"`
+			Data struct {
+				Missing []string `json:"missing"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(ctx.Response.Body(), &resp); err != nil {
+			t.Fatalf("%s: %v", c.body, err)
+		}
+		if resp.Code != c.wantCode || !reflect.DeepEqual(resp.Data.Missing, c.want) {
+			t.Errorf("%s: got %d %q, want %d %q", c.body, resp.Code, resp.Data.Missing, c.wantCode, c.want)
+		}
+	}
+}
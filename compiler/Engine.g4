@@ -1,13 +1,27 @@
 grammar Engine;
 
-expr: logOr EOF;
+// The grammar of the expressions, the lexer states of package token and the binding powers of the operators of the
+// builder are generated from it by `go generate ./compiler`.
+
+expr: cond EOF;
+cond: logOr '?' cond ':' cond | logOr;
 logOr: logOr '||' logAnd | logAnd;
 logAnd: logAnd '&&' logNot | logNot;
 logNot: '!' logNot | cmp;
-cmp: cmp '>' add | cmp '>=' add | cmp '<' add | cmp '<=' add | cmp '==' add | cmp '!=' add | add;
+cmp: cmp '>' bitOr | cmp '>=' bitOr | cmp '<' bitOr | cmp '<=' bitOr | cmp '==' bitOr | cmp '!=' bitOr | cmp 'in' bitOr | cmp NotIn bitOr | cmp '=~' bitOr | cmp '!~' bitOr | bitOr;
+bitOr: bitOr '|' bitXor | bitXor;
+bitXor: bitXor '^' bitAnd | bitAnd;
+bitAnd: bitAnd '&' shift | shift;
+shift: shift '<<' coalesce | shift '>>' coalesce | coalesce;
+coalesce: add '??' coalesce | add '??' logNot | add;
 add: add '+' mul | add '-' mul | mul;
-mul: mul '*' pri | mul '/' pri | mul '%' pri | pri;
-pri: BooleanLiteral|IntegerLiteral|FloatLiteral|StringLiteral|Identifier|'('expr')';
+mul: mul '*' unary | mul '/' unary | mul '%' unary | unary;
+unary: '-' unary | '+' unary | '~' unary | power;
+power: post '**' unary | post;
+post: post '.' Identifier | post '[' cond ']' | pri;
+pri: NullLiteral|BoolLiteral|IntegerLiteral|FloatLiteral|StringLiteral|TimeLiteral|DurationLiteral|Identifier|'(' cond ')'|list|call;
+call: Identifier '(' (cond (',' cond)*)? ')';
+list: '[' (cond (',' cond)*)? ']' | '(' cond (',' cond)+ ')';
 
 
 
@@ -16,6 +30,14 @@ Subtraction                : '-';
 Multiply                   : '*';
 Divide                     : '/';
 Modulus                    : '%';
+Power                      : '**';
+
+BitAnd                     : '&';
+BitOr                      : '|';
+BitXor                     : '^';
+BitNot                     : '~';
+ShiftLeft                  : '<<';
+ShiftRight                 : '>>';
 
 GreaterThan                : '>';
 LessThan                   : '<';
@@ -23,21 +45,39 @@ GreaterEqual               : '>=';
 LessEqual                  : '<=';
 Equal                      : '==';
 NotEqual                   : '!=';
+In                         : 'in';
+NotIn                      : 'not' [ \t\r\n]+ 'in';
+Match                      : '=~';
+NotMatch                   : '!~';
 
 And                        : '&&';
 Or                         : '||';
 Not                        : '!';
+Coalesce                   : '??';
 
 OpenParen                  : '(';
 CloseParen                 : ')';
+OpenBracket                : '[';
+CloseBracket               : ']';
+Comma                      : ',';
+Dot                        : '.';
+Question                   : '?';
+Colon                      : ':';
 
 
-BooleanLiteral: 'true' | 'false';
-IntegerLiteral: '0'| [1-9] [0-9]*;
-FloatLiteral: IntegerLiteral '.' Digit+| '.' Digit+;
+BoolLiteral: 'true' | 'false';
+NullLiteral: 'null';
+IntegerLiteral: Digits | '0' [xX] ('_'? HexDigit)+ | '0' [oO] ('_'? [0-7])+ | '0' [bB] ('_'? [01])+;
+FloatLiteral: Digits '.' Digits Exponent? | '.' Digits Exponent? | Digits Exponent;
 StringLiteral: '"' ~["]* '"'| '\'' ~[\\']* '\''| '`' ~[`]* '`';
+TimeLiteral: '@' Date ('T' Clock ('.' Digit+)? ('Z' | [+-] Digit Digit ':' Digit Digit))?;
+DurationLiteral: (Digits ('.' Digits)? DurationUnit)+;
 Identifier: IdentifierStart IdentifierPart*;
 
+LineComment: '//' ~[\r\n]* -> skip;
+BlockComment: '/*' .*? '*/' -> skip;
+Whitespace: [ \t\r\n]+ -> skip;
+
 fragment IdentifierStart
     : Letter
     | [_]
@@ -57,3 +97,28 @@ fragment Letter
 fragment Digit
     : '0'..'9'
     ;
+
+fragment HexDigit
+    : [0-9a-fA-F]
+    ;
+
+// `_` separates successive digits: 1_000_000
+fragment Digits
+    : Digit ('_'? Digit)*
+    ;
+
+fragment Date
+    : Digit Digit Digit Digit '-' Digit Digit '-' Digit Digit
+    ;
+
+fragment Clock
+    : Digit Digit ':' Digit Digit ':' Digit Digit
+    ;
+
+fragment DurationUnit
+    : 'd' | 'h' | 'm' | 's' | 'ms' | 'us' | 'ns'
+    ;
+
+fragment Exponent
+    : [eE] [+-]? Digits
+    ;
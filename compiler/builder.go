@@ -2,181 +2,419 @@ package compiler
 
 import (
 	"errors"
-	"fmt"
+	"math"
+	"unicode/utf8"
+
 	"github.com/qimengxingyuan/young_engine/executor"
 	"github.com/qimengxingyuan/young_engine/token"
 )
 
-// planner
-type planner func(builder *Builder, curPre *precedence) (*executor.Node, error)
-
-func planValue(builder *Builder, curPre *precedence) (*executor.Node, error) {
-	if !builder.parser.hasNext() {
-		return nil, nil
+// planOperand plans the operand at the current token: a literal, a parameter, a call, a list, a parenthesized
+// expression or a prefix operator with its own operand. The member accesses and indexes which follow are planned
+// with the operand, they bind tighter than any operator.
+func planOperand(builder *Builder) (*executor.Node, error) {
+	start := builder.parser.index
+	tok := builder.parser.next()
+	if power, isPrefix := prefixOperators[tok.Kind]; isPrefix {
+		operand, err := planExpression(builder, power)
+		if err != nil {
+			return nil, err
+		}
+		// the literal of -9223372036854775808 is beyond int64, but the negated literal is the minimum int64
+		if tok.Kind == token.Subtraction && isMinIntOperand(operand) {
+			minInt := executor.NewNodeWithType(nil, nil, executor.LITERAL, int64(math.MinInt64), executor.TypeInteger)
+			return builder.span(minInt, start), nil
+		}
+		return builder.span(executor.NewNode(nil, operand, prefixSymbols[tok.Kind], nil), start), nil
 	}
 
-	tok := builder.parser.next()
+	var node *executor.Node
 	switch tok.Kind {
 	case token.OpenParen:
-		// 最高优先级
-		ret, err := builder.Build()
+		ret, err := builder.build()
 		if err != nil {
 			return nil, err
 		}
 
-		// advance past the CloseParen token. We know that it's a CLAUSE_CLOSE, because at parse-time we check for unbalanced parens.
+		// ("bj", "sh") is a list
+		if builder.parser.peek().Kind == token.Comma {
+			list, err := planList(builder, ret, token.CloseParen)
+			if err != nil {
+				return nil, err
+			}
+			return planPostfix(builder, builder.span(list, start), start)
+		}
+
+		// advance past the CloseParen token, the parser has checked that the parenthesis are balanced
 		builder.parser.next()
+		return planPostfix(builder, builder.span(ret, start), start)
+	case token.OpenBracket:
+		if builder.parser.peek().Kind == token.CloseBracket {
+			builder.parser.next()
+			return planPostfix(builder, builder.span(executor.NewListNode(nil), start), start)
+		}
 
-		// the stage we got represents all of the logic contained within the parens
-		// but for technical reasons, we need to wrap this stage in a "noop" stage which breaks long chains of precedence.
-		// see github #33.
-		node := executor.NewNode(nil, ret, executor.NOOP, nil)
-		return node, nil
+		first, err := builder.build()
+		if err != nil {
+			return nil, err
+		}
+		list, err := planList(builder, first, token.CloseBracket)
+		if err != nil {
+			return nil, err
+		}
+		return planPostfix(builder, builder.span(list, start), start)
 	case token.Identifier:
-		node := executor.NewNode(nil, nil, executor.VALUE, tok.Value)
-		return node, nil
+		if builder.parser.peek().Kind == token.OpenParen {
+			node, err := planCall(builder, tok)
+			if err != nil {
+				return nil, err
+			}
+			return planPostfix(builder, builder.span(node, start), start)
+		}
+		node = executor.NewNode(nil, nil, executor.VALUE, tok.Value)
 	case token.IntegerLiteral:
-		node := executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeInteger)
-		return node, nil
+		tp := executor.TypeInteger
+		if _, isBig := tok.Value.(executor.Decimal); isBig {
+			// the integers beyond int64 are decimals
+			tp = executor.TypeDecimal
+		}
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, tp)
 	case token.FloatLiteral:
-		node := executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeFloat)
-		return node, nil
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeFloat)
 	case token.BoolLiteral:
-		node := executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeBool)
-		return node, nil
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeBool)
 	case token.StringLiteral:
-		node := executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeString)
-		return node, nil
-	case token.Subtraction:
-		ret, err := curPre.plan(builder)
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeString)
+	case token.TimeLiteral:
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeTime)
+	case token.DurationLiteral:
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, tok.Value, executor.TypeDuration)
+	case token.NullLiteral:
+		node = executor.NewNodeWithType(nil, nil, executor.LITERAL, nil, executor.TypeNull)
+	default:
+		return nil, tokenError(SyntaxError, tok, "Unable to plan token kind: '%s', value: '%v'", tok.Kind.String(),
+			tok.Value)
+	}
+	return planPostfix(builder, builder.span(node, start), start)
+}
+
+// isMinIntOperand reports whether the operand of a negation is the integer literal 9223372036854775808.
+func isMinIntOperand(operand *executor.Node) bool {
+	d, isBig := operand.Value().(executor.Decimal)
+	return operand.Symbol() == executor.LITERAL && isBig && d.Neg().Cmp(executor.NewDecimal(math.MinInt64, 0)) == 0
+}
+
+// planList plans the remaining items of a list literal whose first item has already been planned, up to the
+// closing token of the list.
+func planList(builder *Builder, first *executor.Node, closing token.Kind) (*executor.Node, error) {
+	items := []*executor.Node{first}
+	for {
+		tok := builder.parser.next()
+		if tok.Kind == closing {
+			return executor.NewListNode(items), nil
+		}
+		if tok.Kind != token.Comma {
+			return nil, tokenError(SyntaxError, tok, "expected ',' or '%s' in list, but found '%s'", closing.String(),
+				tok.Kind.String())
+		}
+
+		item, err := builder.build()
 		if err != nil {
 			return nil, err
 		}
-		node := executor.NewNodeWithPrefixFix(ret, executor.NEGATIVE, nil)
-		return node, nil
-	case token.Addition: // token.Not,
-		ret, err := curPre.plan(builder)
+		items = append(items, item)
+	}
+}
+
+// planCall plans a call of the function named by the identifier, such as `max(a, b)`. Unknown functions and
+// wrong numbers of arguments are reported at compile time.
+func planCall(builder *Builder, name token.Token) (*executor.Node, error) {
+	fn, exist := builder.functions.Lookup(name.Value.(string))
+	if !exist {
+		return nil, tokenError(SemanticError, name, "unknown function '%v'", name.Value)
+	}
+
+	builder.parser.next() // consume (
+	args := make([]*executor.Node, 0)
+	if builder.parser.peek().Kind == token.CloseParen {
+		builder.parser.next()
+		return newCallNode(name, fn, args)
+	}
+
+	for {
+		arg, err := builder.build()
 		if err != nil {
 			return nil, err
 		}
-		node := executor.NewNodeWithPrefixFix(ret, executor.POSITIVE, nil)
-		return node, nil
-	case token.Not:
-		builder.parser.rewind()
-		return nil, nil
-	default:
-		errorMsg := fmt.Sprintf("Unable to plan token kind: '%s', value: '%v'", tok.Kind.String(), tok.Value)
-		return nil, errors.New(errorMsg)
+		args = append(args, arg)
+
+		tok := builder.parser.next()
+		if tok.Kind == token.CloseParen {
+			return newCallNode(name, fn, args)
+		}
+		if tok.Kind != token.Comma {
+			return nil, tokenError(SyntaxError, tok, "expected ',' or ')' in arguments, but found '%s'", tok.Kind.String())
+		}
 	}
 }
 
-type precedence struct {
-	validKindsToSymbols map[token.Kind]executor.Symbol // 当前优先级的token类型
-	nextPrecedence      *precedence                    // 更高优先级的
-	planner             planner
+// newCallNode returns the node calling fn, a wrong number of arguments is reported at the name of the function.
+func newCallNode(name token.Token, fn *executor.Function, args []*executor.Node) (*executor.Node, error) {
+	node, err := executor.NewCallNode(fn, args)
+	if err != nil {
+		return nil, tokenError(SemanticError, name, "%v", err)
+	}
+	return node, nil
 }
 
-var (
-	// * / %
-	multiplicative = &precedence{
-		validKindsToSymbols: executor.MultiKindsToSymbol,
-		planner:             planValue,
+// planConditional plans both branches of `cond ? yes : no` once the condition has been planned, power is the
+// binding power of the operator. The operator is right-associative, `a ? b : c ? d : e` is `a ? b : (c ? d : e)`.
+func planConditional(builder *Builder, cond *executor.Node, power bindingPower) (*executor.Node, error) {
+	yes, err := builder.build()
+	if err != nil {
+		return nil, err
 	}
 
-	// + -
-	additive = &precedence{
-		validKindsToSymbols: executor.AddKindsToSymbol,
-		nextPrecedence:      multiplicative,
+	if tok := builder.parser.next(); tok.Kind != token.Colon {
+		return nil, tokenError(SyntaxError, tok, "expected ':' in conditional expression, but found '%s'",
+			tok.Kind.String())
 	}
 
-	// > >= < <= == !=
-	comparator = &precedence{
-		validKindsToSymbols: executor.CompareKindsToSymbol,
-		nextPrecedence:      additive,
+	no, err := planExpression(builder, power)
+	if err != nil {
+		return nil, err
 	}
+	return executor.NewConditionalNode(cond, yes, no)
+}
 
-	//!
-	logicalNot = &precedence{
-		validKindsToSymbols: executor.NotKindsToSymbol,
-		nextPrecedence:      comparator,
+// planPostfix plans the member accesses and indexes following an operand, such as `order.items[0].price`
+// or `attrs["x-key"]`. They bind tighter than any operator. start is the index of the first token of the operand.
+func planPostfix(builder *Builder, node *executor.Node, start int) (*executor.Node, error) {
+	for {
+		switch builder.parser.peek().Kind {
+		case token.Dot:
+			builder.parser.next()
+			// the lexer states guarantee that a member name follows the dot
+			name := builder.parser.next()
+			member := executor.NewNodeWithType(nil, nil, executor.LITERAL, name.Value, executor.TypeString)
+			member = builder.span(member, builder.parser.index-1)
+			node = builder.span(executor.NewNode(node, member, executor.MEMBER, nil), start)
+		case token.OpenBracket:
+			builder.parser.next()
+			index, err := builder.build()
+			if err != nil {
+				return nil, err
+			}
+			if tok := builder.parser.next(); tok.Kind != token.CloseBracket {
+				return nil, tokenError(SyntaxError, tok, "expected ']' after index, but found '%s'", tok.Kind.String())
+			}
+			node = builder.span(executor.NewNode(node, index, executor.INDEX, nil), start)
+		default:
+			return node, nil
+		}
 	}
+}
 
-	// &&
-	logicalAnd = &precedence{
-		validKindsToSymbols: executor.AndKindsToSymbol,
-		nextPrecedence:      logicalNot,
-	}
+// bindingPower tells how tightly an operator holds its operands, the operators of a higher power are planned
+// first: `a + b * c` is `a + (b * c)`. The powers are generated from the levels of the rules of Engine.g4 in
+// precedence.go, they are also the levels of the precedence table of the README.
+type bindingPower int
+
+// lowestPower is the power of a whole expression, the one of the conditional operator.
+const lowestPower bindingPower = 0
 
-	// ||
-	logicalOr = &precedence{
-		validKindsToSymbols: executor.OrKindsToSymbol,
-		nextPrecedence:      logicalAnd,
+// infixOperator is an operator between its two operands.
+type infixOperator struct {
+	power bindingPower
+	// rightAssoc groups a chain of the operator from the right, `a ** b ** c` is `a ** (b ** c)`. The other
+	// operators group from the left, `a - b - c` is `(a - b) - c`.
+	rightAssoc bool
+}
+
+// The symbols of the nodes planned for the operators. The operand of a prefix operator is planned up to the first
+// operator which binds less tightly than the prefix operator: `-a * b` is `(-a) * b` but `-a ** 2` is `-(a ** 2)`,
+// and `!a == b` is `!(a == b)`.
+var (
+	infixSymbols = map[token.Kind]executor.Symbol{
+		token.Question:     executor.CONDITIONAL,
+		token.Or:           executor.OR,
+		token.And:          executor.AND,
+		token.GreaterThan:  executor.GT,
+		token.GreaterEqual: executor.GTE,
+		token.LessThan:     executor.LT,
+		token.LessEqual:    executor.LTE,
+		token.Equal:        executor.EQ,
+		token.NotEqual:     executor.NEQ,
+		token.In:           executor.IN,
+		token.NotIn:        executor.NOTIN,
+		token.Match:        executor.MATCH,
+		token.NotMatch:     executor.NOTMATCH,
+		token.BitOr:        executor.BITOR,
+		token.BitXor:       executor.BITXOR,
+		token.BitAnd:       executor.BITAND,
+		token.ShiftLeft:    executor.SHIFTLEFT,
+		token.ShiftRight:   executor.SHIFTRIGHT,
+		token.Coalesce:     executor.COALESCE,
+		token.Addition:     executor.PLUS,
+		token.Subtraction:  executor.MINUS,
+		token.Multiply:     executor.MULTIPLY,
+		token.Divide:       executor.DIVIDE,
+		token.Modulus:      executor.MODULUS,
+		token.Power:        executor.POWER,
 	}
 
-	lowestPrecedence = logicalOr
+	prefixSymbols = map[token.Kind]executor.Symbol{
+		token.Not:         executor.INVERT,
+		token.Subtraction: executor.NEGATIVE,
+		token.Addition:    executor.POSITIVE,
+		token.BitNot:      executor.BITNOT,
+	}
 )
 
-func (p *precedence) plan(builder *Builder) (*executor.Node, error) {
-	var err error
-	var leftNode, rightNode *executor.Node
+// planExpression plans the expression at the current token up to the first operator whose binding power is below
+// minPower, it is the loop of a Pratt parser: once the first operand is planned, each following operator which
+// binds tightly enough takes the node planned so far as its left operand.
+func planExpression(builder *Builder, minPower bindingPower) (*executor.Node, error) {
+	start := builder.parser.index
+	node, err := planOperand(builder)
+	if err != nil {
+		return nil, err
+	}
 
-	if p.nextPrecedence != nil {
-		leftNode, err = p.nextPrecedence.plan(builder)
-		if err != nil {
-			return nil, err
+	for {
+		kind := builder.parser.peek().Kind
+		op, isInfix := infixOperators[kind]
+		if !isInfix || op.power < minPower {
+			return node, nil
 		}
-	} else if p.planner != nil {
-		leftNode, err = p.planner(builder, p)
+		builder.parser.next()
+
+		node, err = planInfix(builder, kind, node, start)
 		if err != nil {
 			return nil, err
 		}
 	}
+}
 
-	for builder.parser.hasNext() {
-		tok := builder.parser.next()
-
-		if tok.Kind.IsEof() {
-			break
+// planInfix plans the right operand of the operator once its left operand has been planned from the token at index
+// start. The right operand of a left-associative operator only takes the operators which bind more tightly.
+func planInfix(builder *Builder, kind token.Kind, left *executor.Node, start int) (*executor.Node, error) {
+	op, symbol := infixOperators[kind], infixSymbols[kind]
+	if symbol == executor.CONDITIONAL {
+		node, err := planConditional(builder, left, op.power)
+		if err != nil {
+			return nil, builder.spanError(err, start)
 		}
+		return builder.span(node, start), nil
+	}
 
-		symbol, exist := p.validKindsToSymbols[tok.Kind]
-		if !exist {
-			break
-		}
+	rightPower := op.power + 1
+	if op.rightAssoc {
+		rightPower = op.power
+	}
+	operand := builder.parser.peek()
+	right, err := planExpression(builder, rightPower)
+	if err != nil {
+		return nil, err
+	}
 
-		rightNode, err = p.plan(builder)
+	if symbol == executor.MATCH || symbol == executor.NOTMATCH {
+		node, err := executor.NewMatchNode(left, right, symbol)
 		if err != nil {
-			return nil, err
+			return nil, tokenError(SemanticError, operand, "%v", err)
 		}
-
-		node := executor.NewNode(leftNode, rightNode, symbol, nil)
-		return node, nil
+		return builder.span(node, start), nil
 	}
-	builder.parser.rewind()
-	return leftNode, nil
+	return builder.span(executor.NewNode(left, right, symbol, nil), start), nil
 }
 
 type Builder struct {
-	rootPlanner *precedence
-	parser      *Parser
+	parser *Parser
+	// functions resolves the names of the called functions, only the built-in functions are known when nil
+	functions *executor.Registry
+	// source is the expression of the tokens, the text of the nodes is empty when it is unknown
+	source []rune
 }
 
 func NewBuilder(p *Parser) *Builder {
 	return &Builder{
-		rootPlanner: lowestPrecedence,
-		parser:      p,
+		parser: p,
 	}
 }
 
-func (b *Builder) Build() (*executor.Node, error) {
-	if b.parser == nil {
-		return nil, errors.New("parse is nil")
+// WithFunctions makes the functions of the registry callable from the expression, on top of the built-in ones.
+func (b *Builder) WithFunctions(r *executor.Registry) *Builder {
+	b.functions = r
+	return b
+}
+
+// WithSource records the expression the tokens are scanned from, the runtime errors of the nodes quote the
+// sub-expression which fails.
+func (b *Builder) WithSource(source string) *Builder {
+	b.source = []rune(source)
+	return b
+}
+
+// span records on the node the part of the expression from the token at index start up to the last consumed
+// token, unless the node already has one such as a parenthesized operand.
+func (b *Builder) span(node *executor.Node, start int) *executor.Node {
+	if !node.Span().IsZero() {
+		return node
+	}
+	span := b.tokenSpan(start)
+	var text string
+	if span.End <= len(b.source) {
+		text = string(b.source[span.Start:span.End])
 	}
+	node.SetSpan(span, text)
+	return node
+}
+
+// tokenSpan returns the span from the token at index start up to the last consumed token.
+func (b *Builder) tokenSpan(start int) executor.Span {
+	last := b.parser.tokens[b.parser.index-1]
+	return executor.Span{
+		Start: b.parser.tokens[start].Position,
+		End:   last.Position + utf8.RuneCountInString(last.Raw),
+	}
+}
 
-	if b.rootPlanner != nil {
-		// TODO 树优化
-		return b.rootPlanner.plan(b)
+// spanError locates the semantic error of a node from the token at index start up to the last consumed token.
+func (b *Builder) spanError(err error, start int) error {
+	var located *CompileError
+	if errors.As(err, &located) {
+		return err
+	}
+	tok := b.parser.tokens[start]
+	e := tokenError(SemanticError, tok, "%v", err)
+	e.span = b.tokenSpan(start).End - tok.Position
+	return e
+}
+
+// Build plans the abstract syntax tree of the parsed tokens, optimizes it and returns it as an immutable program.
+func (b *Builder) Build() (*executor.Program, error) {
+	root, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+
+	// 'a, b' is illegal, the whole expression must have been planned
+	if tok := b.parser.peek(); !tok.Kind.IsEof() {
+		return nil, tokenError(SyntaxError, tok, "unexpected token '%s'", tok.Kind.String())
+	}
+
+	root, err = optimize(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return executor.NewProgram(root)
+}
+
+func (b *Builder) build() (*executor.Node, error) {
+	if b.parser == nil {
+		return nil, errors.New("parse is nil")
 	}
 
-	return nil, errors.New("build failed")
+	return planExpression(b, lowestPower)
 }
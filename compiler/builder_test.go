@@ -0,0 +1,196 @@
+package compiler
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/token"
+)
+
+// planTree returns the tree planned for the expression before the optimization, or the error of the planning.
+func planTree(t *testing.T, exp string) string {
+	tokens, err := NewScanner(exp).Lexer()
+	if err != nil {
+		t.Fatalf("scan %q: %v", exp, err)
+	}
+	parser := NewParser(tokens)
+	if err = parser.ParseSyntax(); err != nil {
+		t.Fatalf("parse %q: %v", exp, err)
+	}
+	builder := NewBuilder(parser)
+	node, err := builder.build()
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if tok := parser.peek(); !tok.Kind.IsEof() {
+		t.Fatalf("plan %q: unexpected token '%s'", exp, tok.Kind.String())
+	}
+	return node.String()
+}
+
+func TestBuilder_Plan(t *testing.T) {
+	cases := []struct {
+		exp  string
+		want string
+	}{
+		// the operators of a level group from the left
+		{exp: `a - b - c`, want: `(- (- a b) c)`},
+		{exp: `a - b + c`, want: `(+ (- a b) c)`},
+		{exp: `a / b * c % d`, want: `(% (* (/ a b) c) d)`},
+		{exp: `a << b >> c`, want: `(>> (<< a b) c)`},
+		{exp: `a & b & c`, want: `(& (& a b) c)`},
+		{exp: `a == b != c`, want: `(!= (= a b) c)`},
+		{exp: `a in b == c`, want: `(= (in a b) c)`},
+		{exp: `a && b && c || d || e`, want: `(|| (|| (&& (&& a b) c) d) e)`},
+		// but `**`, `??` and `?:`
+		{exp: `a ** b ** c`, want: `(** a (** b c))`},
+		{exp: `a ?? b ?? c`, want: `(?? a (?? b c))`},
+		{exp: `a ? b : c ? d : e`, want: `(?: a b (?: c d e))`},
+		// the levels
+		{exp: `a + b * c ** d`, want: `(+ a (* b (** c d)))`},
+		{exp: `a | b ^ c & d << e ?? f + g`, want: `(| a (^ b (& c (<< d (?? e (+ f g))))))`},
+		{exp: `a + b ?? c`, want: `(?? (+ a b) c)`},
+		{exp: `a > b | c`, want: `(> a (| b c))`},
+		{exp: `a || b && c == d`, want: `(|| a (&& b (= c d)))`},
+		{exp: `a || b ? c : d || e`, want: `(?: (|| a b) c (|| d e))`},
+		{exp: `a ? b ? c : d : e`, want: `(?: a (?: b c d) e)`},
+		{exp: `(a + b) * c`, want: `(* (+ a b) c)`},
+		{exp: `a - (b - c)`, want: `(- a (- b c))`},
+		// the operand of a prefix operator only takes the operators which bind more tightly
+		{exp: `-a * b`, want: `(* (- a) b)`},
+		{exp: `-a ** b`, want: `(- (** a b))`},
+		{exp: `a ** -b ** c`, want: `(** a (- (** b c)))`},
+		{exp: `- -a - b`, want: `(- (- (- a)) b)`},
+		{exp: `~a & +b`, want: `(& (~ a) (+ b))`},
+		{exp: `a % -b`, want: `(% a (- b))`},
+		{exp: `-a.b[0]`, want: `(- ([] (. a "b") 0))`},
+		// the negated literal of 9223372036854775808 is the minimum int64
+		{exp: `-9223372036854775808 - a`, want: `(- -9223372036854775808 a)`},
+		{exp: `-9223372036854775809`, want: `(- decimal("9223372036854775809"))`},
+		{exp: `-9223372036854775808 ** 2`, want: `(- (** decimal("9223372036854775808") 2))`},
+		{exp: `!a == b && c`, want: `(&& (! (= a b)) c)`},
+		{exp: `!!a || b`, want: `(|| (! (! a)) b)`},
+		{exp: `a ?? !b`, want: `(?? a (! b))`},
+		{exp: `a ?? !b || c`, want: `(|| (?? a (! b)) c)`},
+		// the member accesses and indexes bind tighter than any operator
+		{exp: `a.b + c[0].d`, want: `(+ (. a "b") (. ([] c 0) "d"))`},
+		{exp: `(a ?? b).c`, want: `(. (?? a b) "c")`},
+		{exp: `[a, b][0]`, want: `([] [a b] 0)`},
+		{exp: `"abc"[0] + []`, want: `(+ ([] "abc" 0) [])`},
+		{exp: `max(a, b - c - d)`, want: `max(a, (- (- b c) d))`},
+		{exp: `a in ("x", "y") && b not in []`, want: `(&& (in a ["x" "y"]) (not in b []))`},
+	}
+
+	for _, c := range cases {
+		if got := planTree(t, c.exp); got != c.want {
+			t.Errorf("%q: got %s, want %s", c.exp, got, c.want)
+		}
+	}
+}
+
+// TestBuilder_Corpus plans the expressions of the other tests and of the README, the trees are kept in
+// testdata/trees.txt.
+func TestBuilder_Corpus(t *testing.T) {
+	f, err := os.Open("testdata/trees.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		quoted, want, found := strings.Cut(line, "\t")
+		exp, err := strconv.Unquote(quoted)
+		if !found || err != nil {
+			t.Fatalf("malformed line %q", line)
+		}
+		if got := planTree(t, exp); got != want {
+			t.Errorf("%q: got %s, want %s", exp, got, want)
+		}
+		count++
+	}
+	if count < 500 {
+		t.Errorf("only %d expressions in the corpus", count)
+	}
+}
+
+// TestBuilder_PrecedenceTable checks the binding powers and the associativity of the operators against the
+// precedence table of the README, whose levels are the binding powers.
+func TestBuilder_PrecedenceTable(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[string]token.Kind)
+	for k := token.Kind(0); k < token.KindEnd; k++ {
+		kinds[k.String()] = k
+	}
+	kinds["? :"] = token.Question
+
+	row := regexp.MustCompile("(?m)^\\| (\\d+) +\\| (.+?) +\\| (左|右)? *\\|$")
+	operator := regexp.MustCompile("`([^`]+)`")
+	infix, prefix := make(map[token.Kind]bool), make(map[token.Kind]bool)
+	for _, m := range row.FindAllStringSubmatch(string(readme), -1) {
+		level, _ := strconv.Atoi(m[1])
+		unary := strings.HasPrefix(m[2], "一元")
+		for _, op := range operator.FindAllStringSubmatch(m[2], -1) {
+			text := strings.ReplaceAll(op[1], `\|`, "|")
+			kind, exist := kinds[text]
+			if !exist {
+				t.Errorf("level %d: unknown operator %q", level, text)
+				continue
+			}
+
+			if infixOp, isInfix := infixOperators[kind]; isInfix && !unary {
+				infix[kind] = true
+				if int(infixOp.power) != level || infixOp.rightAssoc != (m[3] == "右") {
+					t.Errorf("%q: got power %d right-associative %v, the README has level %d %q", text,
+						infixOp.power, infixOp.rightAssoc, level, m[3])
+				}
+				continue
+			}
+			power, isPrefix := prefixOperators[kind]
+			if !isPrefix {
+				t.Errorf("level %d: %q is not an operator of the builder", level, text)
+				continue
+			}
+			prefix[kind] = true
+			if int(power) != level {
+				t.Errorf("prefix %q: got power %d, the README has level %d", text, power, level)
+			}
+		}
+	}
+
+	if len(infix) != len(infixOperators) || len(prefix) != len(prefixOperators) {
+		t.Errorf("the README has %d of %d infix operators and %d of %d prefix operators", len(infix),
+			len(infixOperators), len(prefix), len(prefixOperators))
+	}
+}
+
+// TestBuilder_Symbols checks that the builder has a symbol for each operator generated from Engine.g4, and no other.
+func TestBuilder_Symbols(t *testing.T) {
+	for kind := range infixOperators {
+		if _, exist := infixSymbols[kind]; !exist {
+			t.Errorf("no symbol for the infix operator %q", kind.String())
+		}
+	}
+	for kind := range prefixOperators {
+		if _, exist := prefixSymbols[kind]; !exist {
+			t.Errorf("no symbol for the prefix operator %q", kind.String())
+		}
+	}
+	if len(infixSymbols) != len(infixOperators) || len(prefixSymbols) != len(prefixOperators) {
+		t.Errorf("got %d infix and %d prefix symbols for %d infix and %d prefix operators of the grammar",
+			len(infixSymbols), len(prefixSymbols), len(infixOperators), len(prefixOperators))
+	}
+}
@@ -0,0 +1,62 @@
+package compiler
+
+import (
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+// Engine compiles expressions which may call the functions registered into it. Engines are independent, a
+// function registered into one of them is unknown to the expressions compiled by the others.
+type Engine struct {
+	functions *executor.Registry
+	// maxErrors is the maximum number of errors reported by Compile
+	maxErrors int
+}
+
+func NewEngine() *Engine {
+	return &Engine{
+		functions: executor.NewRegistry(),
+		maxErrors: DefaultMaxErrors,
+	}
+}
+
+// WithMaxErrors limits the number of errors reported by Compile to n, DefaultMaxErrors by default. All the errors
+// are reported when n is 0 or less.
+func (e *Engine) WithMaxErrors(n int) *Engine {
+	e.maxErrors = n
+	return e
+}
+
+// Register makes the go function fn callable by name from the expressions compiled afterwards, e.g.
+//
+//	engine.Register("riskScore", []executor.TypeFlags{executor.TypeInteger}, executor.TypeFloat, riskScore)
+//
+// The arguments are checked against params before fn is called, and its result against ret. An error returned
+// by fn, or a panic in it, fails the evaluation of the expression.
+func (e *Engine) Register(name string, params []executor.TypeFlags, ret executor.TypeFlags, fn executor.GoFunc) error {
+	return e.functions.Register(name, params, ret, fn)
+}
+
+// Compile scans, parses and builds the expression into a program. The error is an ErrorList of the errors
+// located in the expression: all the lexical and syntax errors, or the first error found by the builder.
+func (e *Engine) Compile(exp string) (*executor.Program, error) {
+	program, err := e.compile(exp)
+	if err != nil {
+		return nil, compileErrors(err, []rune(exp), e.maxErrors)
+	}
+	return program, nil
+}
+
+func (e *Engine) compile(exp string) (*executor.Program, error) {
+	// the parser checks the tokens of the scanner in spite of its errors, to report the syntax errors too
+	var errs ErrorList
+	tokens, err := NewScanner(exp).Lexer()
+	errs.add(err)
+
+	parser := NewParser(tokens)
+	errs.add(parser.ParseSyntax())
+	if err = errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewBuilder(parser).WithFunctions(e.functions).WithSource(exp).Build()
+}
@@ -0,0 +1,160 @@
+package compiler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+var errBackend = errors.New("backend unavailable")
+
+func newTestEngine(t *testing.T) *Engine {
+	e := NewEngine()
+	funcs := []struct {
+		name   string
+		params []executor.TypeFlags
+		ret    executor.TypeFlags
+		fn     executor.GoFunc
+	}{
+		{"riskScore", []executor.TypeFlags{executor.TypeInteger}, executor.TypeFloat,
+			func(args ...interface{}) (interface{}, error) {
+				return float64(args[0].(int64)%100) / 100, nil
+			}},
+		{"isBlacklisted", []executor.TypeFlags{executor.TypeString}, executor.TypeBool,
+			func(args ...interface{}) (interface{}, error) {
+				return args[0].(string) == "10.0.0.1", nil
+			}},
+		{"fetch", []executor.TypeFlags{executor.TypeString}, executor.TypeString,
+			func(args ...interface{}) (interface{}, error) {
+				return nil, errBackend
+			}},
+		{"explode", nil, executor.TypeBool,
+			func(args ...interface{}) (interface{}, error) {
+				panic("boom")
+			}},
+		{"liar", nil, executor.TypeBool,
+			func(args ...interface{}) (interface{}, error) {
+				return 1, nil
+			}},
+	}
+	for _, f := range funcs {
+		if err := e.Register(f.name, f.params, f.ret, f.fn); err != nil {
+			t.Fatalf("register %s: %v", f.name, err)
+		}
+	}
+	return e
+}
+
+func TestEngine_Call(t *testing.T) {
+	e := newTestEngine(t)
+	params := executor.MapParameters{"uid": 1042, "ip": "10.0.0.1"}
+	cases := []struct {
+		exp  string
+		want interface{}
+	}{
+		{exp: `riskScore(uid) > 0.4`, want: true},
+		{exp: `riskScore(uid + 1)`, want: 0.43},
+		{exp: `isBlacklisted(ip) && len(ip) > 3`, want: true},
+		{exp: `isBlacklisted("127.0.0.1")`, want: false},
+	}
+	for _, c := range cases {
+		program, err := e.Compile(c.exp)
+		if err != nil {
+			t.Fatalf("compile %q: %v", c.exp, err)
+		}
+		got, _, err := program.Eval(params)
+		if err != nil {
+			t.Fatalf("eval %q: %v", c.exp, err)
+		}
+		if got != c.want {
+			t.Errorf("eval %q = %v, want %v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestEngine_CallError(t *testing.T) {
+	e := newTestEngine(t)
+	cases := []struct {
+		exp string
+		err string
+	}{
+		{exp: `riskScore("1042")`, err: "type mismatch for function [riskScore]: arg 1='string', expected 'int'"},
+		{exp: `fetch("x") == ""`, err: "function [fetch]: backend unavailable"},
+		{exp: `explode()`, err: "engine: function [explode] panicked: boom"},
+		{exp: `liar()`, err: "engine: function [liar] returned 'int', expected 'boolean'"},
+	}
+	for _, c := range cases {
+		program, err := e.Compile(c.exp)
+		if err != nil {
+			t.Fatalf("compile %q: %v", c.exp, err)
+		}
+		_, _, err = program.Eval(executor.DummyParameters)
+		var evalErr *executor.EvalError
+		if !errors.As(err, &evalErr) || evalErr.Err.Error() != c.err {
+			t.Errorf("eval %q: got error %v, want %q", c.exp, err, c.err)
+		}
+	}
+
+	program, err := e.Compile(`fetch("x")`)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if _, _, err = program.Eval(executor.DummyParameters); !errors.Is(err, errBackend) {
+		t.Errorf("the error of the function is not wrapped: %v", err)
+	}
+}
+
+func TestEngine_CompileError(t *testing.T) {
+	e := newTestEngine(t)
+	cases := []struct {
+		exp string
+		err string
+	}{
+		{exp: `unknown(1)`, err: "unknown function 'unknown' at position 0"},
+		{exp: "riskScore(uid) > 0.5 // the model\n  && unknown(1)",
+			err: "unknown function 'unknown' at position 39 (line 2, column 6)"},
+		{exp: `riskScore()`, err: "function [riskScore] expects 1 arguments, but got 0 at position 0"},
+		{exp: `isBlacklisted(ip, 1)`, err: "function [isBlacklisted] expects 1 arguments, but got 2 at position 0"},
+	}
+	for _, c := range cases {
+		_, err := e.Compile(c.exp)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("compile %q: got error %v, want %q", c.exp, err, c.err)
+		}
+	}
+
+	// the functions of an engine are unknown to the others
+	if _, err := NewEngine().Compile(`riskScore(uid)`); err == nil {
+		t.Errorf("compile with another engine: expected an unknown function error")
+	}
+}
+
+func TestEngine_Register(t *testing.T) {
+	e := newTestEngine(t)
+	fn := func(args ...interface{}) (interface{}, error) { return true, nil }
+	cases := []struct {
+		name   string
+		params []executor.TypeFlags
+		ret    executor.TypeFlags
+		fn     executor.GoFunc
+		err    string
+	}{
+		{name: "riskScore", ret: executor.TypeBool, fn: fn, err: "is already registered"},
+		{name: "len", ret: executor.TypeBool, fn: fn, err: "is a built-in function"},
+		{name: "in", ret: executor.TypeBool, fn: fn, err: "invalid function name"},
+		{name: "is-risky", ret: executor.TypeBool, fn: fn, err: "invalid function name"},
+		{name: "1st", ret: executor.TypeBool, fn: fn, err: "invalid function name"},
+		{name: "ok", ret: executor.TypeBool, err: "is nil"},
+		{name: "ok", ret: executor.TypeNull, fn: fn, err: "invalid return type"},
+		{name: "ok", params: []executor.TypeFlags{executor.TypeNull}, ret: executor.TypeBool, fn: fn,
+			err: "invalid type for parameter 1"},
+	}
+	for _, c := range cases {
+		err := e.Register(c.name, c.params, c.ret, c.fn)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("register %q: got error %v, want %q", c.name, err, c.err)
+		}
+	}
+}
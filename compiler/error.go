@@ -0,0 +1,249 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+	"github.com/qimengxingyuan/young_engine/token"
+)
+
+// DefaultMaxErrors is the maximum number of errors reported by the compilation of an expression, see
+// Engine.WithMaxErrors.
+const DefaultMaxErrors = 10
+
+// ErrorKind tells which stage of the compilation rejected the expression.
+type ErrorKind int
+
+const (
+	// LexicalError is reported by the scanner: an illegal character, a malformed literal or an unterminated string
+	LexicalError ErrorKind = iota
+	// SyntaxError is reported by the parser and the builder: unbalanced parenthesis, an unexpected token
+	SyntaxError
+	// SemanticError is reported for a well-formed expression: an unknown function, a type mismatch
+	SemanticError
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case LexicalError:
+		return "lexical"
+	case SyntaxError:
+		return "syntax"
+	case SemanticError:
+		return "semantic"
+	}
+	return "unknown"
+}
+
+// MarshalText renders the kind by its name in JSON.
+func (k ErrorKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// CompileError is the error returned by Engine.Compile. It locates the span of the source which is rejected, so
+// that an editor can underline it, e.g. for `age > 18 && unknown(1)`:
+//
+//	age > 18 && unknown(1)
+//	            ^~~~~~~
+//
+// Line is 0 for the errors which are not located in the source, such as too many errors.
+type CompileError struct {
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"message"`
+	// Position is the offset of the first character of the span, counted in characters like token.Token
+	Position int `json:"position"`
+	// Offset and Length are the span in bytes of the UTF-8 source
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	// Line and Column locate the first character of the span, both start at 1
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Snippet is the line of the span followed by a line with a caret under the span
+	Snippet string `json:"snippet"`
+
+	span    int  // the length of the span in characters
+	located bool // whether the error is located in the source
+}
+
+func (e *CompileError) Error() string {
+	if !e.located {
+		return e.Message
+	}
+	return e.Message + " at " + at(e.Position, e.Line, e.Column)
+}
+
+// at describes a position of the source in the error messages. The line and the column are only added beyond the
+// first line of a multi-line expression, where the position alone is hard to find.
+func at(position, line, column int) string {
+	if line <= 1 {
+		return fmt.Sprintf("position %d", position)
+	}
+	return fmt.Sprintf("position %d (line %d, column %d)", position, line, column)
+}
+
+// tokenError returns an error located at the span of the token. The span is in the source once it is located by
+// locate.
+func tokenError(kind ErrorKind, tok token.Token, format string, args ...interface{}) *CompileError {
+	line, column := tok.Line, tok.Column
+	if line == 0 {
+		// the tokens which are not scanned, such as the Eof of the parser
+		line, column = 1, tok.Position+1
+	}
+	return &CompileError{
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...),
+		Position: tok.Position,
+		Line:     line,
+		Column:   column,
+		span:     utf8.RuneCountInString(tok.Raw),
+		located:  true,
+	}
+}
+
+// spanError returns an error located at the span of a node, its line and its column are computed by locate.
+func spanError(kind ErrorKind, span executor.Span, err error) *CompileError {
+	return &CompileError{
+		Kind:     kind,
+		Message:  err.Error(),
+		Position: span.Start,
+		span:     span.End - span.Start,
+		located:  true,
+	}
+}
+
+// ErrorList is the list of the errors of a compilation. The scanner and the parser recover from the errors so
+// that all of them are reported at once.
+type ErrorList []*CompileError
+
+// Error describes the first error and counts the others, the errors are listed by the list itself.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns the list as an error, or nil if it is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// add appends err to the list, the errors of an ErrorList one by one. The errors of the other types are the
+// semantic errors, which are not located in the source unless they are raised by the evaluation of a node.
+func (l *ErrorList) add(err error) {
+	var list ErrorList
+	var e *CompileError
+	var evalErr *executor.EvalError
+	switch {
+	case err == nil:
+	case errors.As(err, &list):
+		*l = append(*l, list...)
+	case errors.As(err, &e):
+		*l = append(*l, e)
+	case errors.As(err, &evalErr):
+		// an error of the folding of constants, such as `1 / 0`
+		*l = append(*l, spanError(SemanticError, evalErr.Span, evalErr.Err))
+	default:
+		*l = append(*l, &CompileError{Kind: SemanticError, Message: err.Error()})
+	}
+}
+
+// sort sorts the errors by their position in the source, the errors which are not located come last. Only the
+// first error is kept at a position, the others are the consequences of the same mistake.
+func (l *ErrorList) sort() {
+	sort.SliceStable(*l, func(i, j int) bool {
+		a, b := (*l)[i], (*l)[j]
+		if !a.located || !b.located {
+			return a.located && !b.located
+		}
+		return a.Position < b.Position
+	})
+
+	list := (*l)[:0]
+	for i, e := range *l {
+		if i > 0 && e.located && e.Position == list[len(list)-1].Position {
+			continue
+		}
+		list = append(list, e)
+	}
+	*l = list
+}
+
+// compileErrors returns the errors of err sorted and located in the source. Beyond max errors, the others are
+// replaced by a last error telling that there are too many of them.
+func compileErrors(err error, source []rune, max int) ErrorList {
+	var list ErrorList
+	list.add(err)
+	list.sort()
+	for _, e := range list {
+		e.locate(source)
+	}
+	if max > 0 && len(list) > max {
+		list = append(list[:max:max], &CompileError{Kind: list[max].Kind, Message: "too many errors"})
+	}
+	return list
+}
+
+// locate computes the byte offsets and the snippet of the span in the source.
+func (e *CompileError) locate(source []rune) {
+	if !e.located || e.Snippet != "" {
+		return
+	}
+
+	start, end := e.Position, e.Position+e.span
+	if end > len(source) {
+		end = len(source)
+	}
+	if start > end {
+		start = end
+	}
+	e.Offset = len(string(source[:start]))
+	e.Length = len(string(source[start:end]))
+	if e.Line == 0 {
+		// the errors located at the span of a node
+		e.Line, e.Column = 1, start+1
+		for i, ch := range source[:start] {
+			if ch == '\n' {
+				e.Line, e.Column = e.Line+1, start-i
+			}
+		}
+	}
+
+	lineStart := start - (e.Column - 1)
+	if lineStart < 0 {
+		lineStart = 0
+	}
+	lineEnd := start
+	for lineEnd < len(source) && source[lineEnd] != '\n' {
+		lineEnd++
+	}
+
+	var caret strings.Builder
+	for _, ch := range source[lineStart:start] {
+		// keep the tabs so that the caret is aligned whatever their width
+		if ch == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+	// the span of a token never goes beyond its line but the one of a comment
+	if end > lineEnd {
+		end = lineEnd
+	}
+	if end-start > 1 {
+		caret.WriteString(strings.Repeat("~", end-start-1))
+	}
+	e.Snippet = strings.TrimSuffix(string(source[lineStart:lineEnd]), "\r") + "\n" + caret.String()
+}
@@ -0,0 +1,132 @@
+package compiler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileError(t *testing.T) {
+	cases := []struct {
+		exp  string
+		want CompileError
+	}{
+		{exp: `age > 18 && unknown(1)`, want: CompileError{Kind: SemanticError, Message: "unknown function 'unknown'",
+			Position: 12, Offset: 12, Length: 7, Line: 1, Column: 13,
+			Snippet: "age > 18 && unknown(1)\n            ^~~~~~~"}},
+		{exp: `a b`, want: CompileError{Kind: SyntaxError,
+			Message:  "cannot transition token types from Identifier [a] to Identifier [b]",
+			Position: 2, Offset: 2, Length: 1, Line: 1, Column: 3, Snippet: "a b\n  ^"}},
+		{exp: `(a + (b > c)`, want: CompileError{Kind: SyntaxError, Message: "unbalanced parenthesis",
+			Position: 0, Offset: 0, Length: 1, Line: 1, Column: 1, Snippet: "(a + (b > c)\n^"}},
+		// the offsets are in bytes, the positions and the columns in characters
+		{exp: "城市 == \"北京\" &&\n\tscore > 1e400", want: CompileError{Kind: LexicalError,
+			Message:  "float literal '1e400' is out of range",
+			Position: 23, Offset: 31, Length: 5, Line: 2, Column: 10, Snippet: "\tscore > 1e400\n\t        ^~~~~"}},
+		{exp: "a > 1 /* b\n c", want: CompileError{Kind: LexicalError, Message: "comment not terminated",
+			Position: 6, Offset: 6, Length: 7, Line: 1, Column: 7, Snippet: "a > 1 /* b\n      ^~~~"}},
+		{exp: `a +`, want: CompileError{Kind: SyntaxError,
+			Message:  "cannot transition token types from + [43] to Eof [<nil>]",
+			Position: 3, Offset: 3, Length: 0, Line: 1, Column: 4, Snippet: "a +\n   ^"}},
+		// the errors of the folding of constants are located at the span of the node
+		{exp: `x > 1 + "a"`, want: CompileError{Kind: SemanticError,
+			Message:  "type mismatch for operator [+]: left='int', right='string'",
+			Position: 4, Offset: 4, Length: 7, Line: 1, Column: 5, Snippet: "x > 1 + \"a\"\n    ^~~~~~~"}},
+		{exp: "x > 0 &&\n  x < 10 / 0", want: CompileError{Kind: SemanticError, Message: "engine: number divide by zero",
+			Position: 15, Offset: 15, Length: 6, Line: 2, Column: 7, Snippet: "  x < 10 / 0\n      ^~~~~~"}},
+	}
+
+	for _, c := range cases {
+		_, err := NewEngine().Compile(c.exp)
+		var list ErrorList
+		if !errors.As(err, &list) || len(list) != 1 {
+			t.Errorf("%q: got error %v, want an ErrorList of one error", c.exp, err)
+			continue
+		}
+		got := list[0]
+		// the span in characters is internal
+		got.span, got.located = 0, false
+		if *got != c.want {
+			t.Errorf("%q: got %+v, want %+v", c.exp, *got, c.want)
+		}
+	}
+}
+
+func TestCompileError_JSON(t *testing.T) {
+	_, err := NewEngine().Compile("uid in [1, 2\n")
+	b, _ := json.Marshal(err)
+	want := `[{"kind":"syntax","message":"unbalanced brackets","position":7,"offset":7,"length":1,"line":1,"column":8,` +
+		`"snippet":"uid in [1, 2\n       ^"}]`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+	if err.Error() != "unbalanced brackets at position 7" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	cases := []struct {
+		exp  string
+		errs []string
+	}{
+		{exp: `a b c + d e`, errs: []string{
+			"cannot transition token types from Identifier [a] to Identifier [b] at position 2",
+			"cannot transition token types from Identifier [d] to Identifier [e] at position 10",
+		}},
+		{exp: "a > 0x1G &&\n\tb # 1 &&\n\tc == \"x", errs: []string{
+			"invalid digit 'G' in hexadecimal literal at position 7",
+			"the scan found an illegal character '#' at position 15 (line 2, column 4)",
+			"string literal not terminated at position 28 (line 3, column 7)",
+		}},
+		{exp: `(a + ] * [b, c) + (`, errs: []string{
+			"unbalanced brackets at position 5",
+			"unbalanced brackets at position 9",
+			"unbalanced parenthesis at position 18",
+			"cannot transition token types from ( [40] to Eof [<nil>] at position 19",
+		}},
+		{exp: `1.5. > x && y = 2 && z ~ `, errs: []string{
+			"unexpected '.' in numeric literal at position 3",
+			"expected to get '==' or '=~', but only found '=' at position 14",
+			"cannot transition token types from Identifier [z] to ~ [126] at position 23",
+			"cannot transition token types from ~ [126] to Eof [<nil>] at position 25",
+		}},
+		// the builder stops at its first error
+		{exp: `unknown(1) + unknown(2)`, errs: []string{"unknown function 'unknown' at position 0"}},
+	}
+
+	for _, c := range cases {
+		_, err := NewEngine().Compile(c.exp)
+		var list ErrorList
+		if !errors.As(err, &list) {
+			t.Errorf("%q: got error %v, want an ErrorList", c.exp, err)
+			continue
+		}
+		got := make([]string, len(list))
+		for i, e := range list {
+			got[i] = e.Error()
+		}
+		if !reflect.DeepEqual(got, c.errs) {
+			t.Errorf("%q: got errors %q, want %q", c.exp, got, c.errs)
+		}
+	}
+}
+
+func TestEngine_WithMaxErrors(t *testing.T) {
+	exp := `a b + c d + e f + g h`
+	_, err := NewEngine().WithMaxErrors(2).Compile(exp)
+	list := err.(ErrorList)
+	if len(list) != 3 || list[2].Message != "too many errors" || list[2].Line != 0 {
+		t.Errorf("got %d errors: %v", len(list), list)
+	}
+	if !strings.HasSuffix(err.Error(), "at position 2 (and 2 more errors)") {
+		t.Errorf("got error %v", err)
+	}
+
+	_, err = NewEngine().WithMaxErrors(0).Compile(exp)
+	if list := err.(ErrorList); len(list) != 4 {
+		t.Errorf("got %d errors without a limit, want 4", len(list))
+	}
+}
@@ -5,6 +5,24 @@ import (
 	"unicode/utf8"
 )
 
+// syncChars are the first characters of the operators and the parenthesis, the scanner resumes at them after an
+// error. The dot is not one of them, it is a part of the malformed numbers such as `7.7.7`.
+const syncChars = "+-*/%()[],?:^~<>=!&|"
+
+// simpleEscapes maps the character following a backslash to the string it stands for, e.g. `\n` is a newline.
+var simpleEscapes = map[rune]string{
+	'a':  "\a",
+	'b':  "\b",
+	'f':  "\f",
+	'n':  "\n",
+	'r':  "\r",
+	't':  "\t",
+	'v':  "\v",
+	'\\': "\\",
+	'\'': "'",
+	'"':  "\"",
+}
+
 func lower(ch rune) rune { return ('a' - 'A') | ch } // returns lower-case ch iff ch is ASCII letter
 
 // isLetter reports whether a given 'rune' is classified as a Letter.
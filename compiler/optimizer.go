@@ -0,0 +1,253 @@
+package compiler
+
+import (
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+// optimize simplifies the abstract syntax tree produced by the planner:
+//   - POSITIVE on numbers is removed
+//   - the patterns of `=~` and `!~` folded into a literal, such as `"^" + "a"`, are compiled
+//   - subtrees, lists and calls of pure functions made of literals only are folded into a single literal with
+//     the operators of the executor, so errors such as a literal divided by zero are reported at compile time,
+//     except the ones which round decimals since the rounding mode is chosen after the compilation
+//   - double negation `!!x` collapses to `x`
+//   - `true && x` and `false || x` become `x`, `false && x` and `true || x` become the literal
+//   - a conditional with a literal condition becomes the selected branch, `x ?? y` becomes x when x is a literal
+//     other than null and y when x is null
+//
+// The simplifications never change the result of an evaluation. When the type of an operand is only known at
+// run time the node which checks it is kept, e.g. `!!flag` becomes `!flag` when flag is not a boolean.
+func optimize(n *executor.Node) (*executor.Node, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	switch n.Symbol() {
+	case executor.VALUE, executor.LITERAL:
+		return n, nil
+	case executor.AND, executor.OR:
+		return optimizeLogical(n)
+	case executor.LIST:
+		return optimizeList(n)
+	case executor.CALL:
+		return optimizeCall(n)
+	case executor.CONDITIONAL:
+		return optimizeConditional(n)
+	case executor.COALESCE:
+		return optimizeCoalesce(n)
+	}
+
+	left, err := optimize(n.Left())
+	if err != nil {
+		return nil, err
+	}
+	right, err := optimize(n.Right())
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Symbol() {
+	case executor.POSITIVE:
+		if tp, known := right.StaticType(); known && tp.IsNumber() {
+			return right, nil
+		}
+	case executor.INVERT:
+		if right.Symbol() == executor.INVERT {
+			operand := right.Right()
+			if tp, known := operand.StaticType(); known && tp.IsBool() {
+				return operand, nil
+			}
+			if operand.Symbol() == executor.INVERT {
+				// `!!!x` is `!x`, the remaining negation still checks the type of x
+				return operand, nil
+			}
+		}
+	}
+
+	if n.Symbol() == executor.MATCH || n.Symbol() == executor.NOTMATCH {
+		if n.Value() != nil {
+			// the literal pattern has already been compiled by the planner
+			return fold(rebuilt(n, executor.NewNode(left, right, n.Symbol(), n.Value())))
+		}
+		match, err := executor.NewMatchNode(left, right, n.Symbol())
+		if err != nil {
+			return nil, err
+		}
+		return fold(rebuilt(n, match))
+	}
+
+	return fold(rebuilt(n, executor.NewNode(left, right, n.Symbol(), nil)))
+}
+
+// rebuilt gives the span of the node n to the node rebuilt from it, so that the errors of the rebuilt node are
+// still located in the expression.
+func rebuilt(n, node *executor.Node) *executor.Node {
+	node.SetSpan(n.Span(), n.Text())
+	return node
+}
+
+// optimizeLogical simplifies `&&` and `||`. The right operand is dropped when a literal left operand decides the
+// result, exactly like it is skipped at run time, so errors in it are not reported.
+func optimizeLogical(n *executor.Node) (*executor.Node, error) {
+	left, err := optimize(n.Left())
+	if err != nil {
+		return nil, err
+	}
+
+	if left.Symbol() == executor.LITERAL && left.Type().IsBool() {
+		decided := left.Value().(bool) == (n.Symbol() == executor.OR)
+		if decided {
+			return fold(rebuilt(n, executor.NewNode(left, n.Right(), n.Symbol(), nil)))
+		}
+
+		right, err := optimize(n.Right())
+		if err != nil {
+			return nil, err
+		}
+		if tp, known := right.StaticType(); known && tp.IsBool() {
+			return right, nil
+		}
+		return fold(rebuilt(n, executor.NewNode(left, right, n.Symbol(), nil)))
+	}
+
+	right, err := optimize(n.Right())
+	if err != nil {
+		return nil, err
+	}
+	return fold(rebuilt(n, executor.NewNode(left, right, n.Symbol(), nil)))
+}
+
+// optimizeConditional keeps the selected branch only when the condition is a literal. The other branch is dropped
+// like it is skipped at run time, so errors in it are not reported.
+func optimizeConditional(n *executor.Node) (*executor.Node, error) {
+	children := n.Children()
+	cond, err := optimize(children[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if cond.Symbol() == executor.LITERAL && cond.Type().IsBool() {
+		if cond.Value().(bool) {
+			return optimize(children[1])
+		}
+		return optimize(children[2])
+	}
+
+	yes, err := optimize(children[1])
+	if err != nil {
+		return nil, err
+	}
+	no, err := optimize(children[2])
+	if err != nil {
+		return nil, err
+	}
+	node, err := executor.NewConditionalNode(cond, yes, no)
+	if err != nil {
+		return nil, err
+	}
+	return rebuilt(n, node), nil
+}
+
+// optimizeCoalesce drops the operand of `??` which is never the result when the left operand is a literal.
+func optimizeCoalesce(n *executor.Node) (*executor.Node, error) {
+	left, err := optimize(n.Left())
+	if err != nil {
+		return nil, err
+	}
+	if left.Symbol() == executor.LITERAL {
+		if left.Type().IsNull() {
+			return optimize(n.Right())
+		}
+		return left, nil
+	}
+
+	right, err := optimize(n.Right())
+	if err != nil {
+		return nil, err
+	}
+	return rebuilt(n, executor.NewNode(left, right, executor.COALESCE, nil)), nil
+}
+
+// optimizeList folds a list literal whose items are all literals, such as `[1001, 1002]`.
+func optimizeList(n *executor.Node) (*executor.Node, error) {
+	items, constant, err := optimizeChildren(n.Children())
+	if err != nil {
+		return nil, err
+	}
+
+	list := rebuilt(n, executor.NewListNode(items))
+	if !constant {
+		return list, nil
+	}
+
+	val, tp, err := list.Eval(executor.DummyParameters)
+	if err != nil {
+		return nil, err
+	}
+	return rebuilt(n, executor.NewNodeWithType(nil, nil, executor.LITERAL, val, tp)), nil
+}
+
+// optimizeCall folds the call of a pure function whose arguments are all literals, such as `max(1, 2)`.
+func optimizeCall(n *executor.Node) (*executor.Node, error) {
+	args, constant, err := optimizeChildren(n.Children())
+	if err != nil {
+		return nil, err
+	}
+
+	call, err := executor.NewCallNode(n.Function(), args)
+	if err != nil {
+		return nil, err
+	}
+	call = rebuilt(n, call)
+	if !constant || !n.Function().IsPure() || call.Rounds() {
+		return call, nil
+	}
+
+	val, tp, err := call.Eval(executor.DummyParameters)
+	if err != nil {
+		return nil, err
+	}
+	return rebuilt(call, executor.NewNodeWithType(nil, nil, executor.LITERAL, val, tp)), nil
+}
+
+// optimizeChildren optimizes the items of a list or the arguments of a call and reports whether they are all
+// literals.
+func optimizeChildren(children []*executor.Node) ([]*executor.Node, bool, error) {
+	constant := true
+	optimized := make([]*executor.Node, len(children))
+	for i, child := range children {
+		item, err := optimize(child)
+		if err != nil {
+			return nil, false, err
+		}
+		optimized[i] = item
+		constant = constant && isConstant(item)
+	}
+	return optimized, constant, nil
+}
+
+// fold evaluates the node when all of its operands are literals and replaces it with the result. A node whose
+// left operand decides the result of `&&` and `||` is folded as well, whatever the right operand is.
+func fold(n *executor.Node) (*executor.Node, error) {
+	if !isConstant(n.Left()) || n.Rounds() {
+		return n, nil
+	}
+
+	if !isConstant(n.Right()) {
+		decided := (n.Symbol() == executor.AND || n.Symbol() == executor.OR) &&
+			n.Left().Type().IsBool() && n.Left().Value().(bool) == (n.Symbol() == executor.OR)
+		if !decided {
+			return n, nil
+		}
+	}
+
+	val, tp, err := n.Eval(executor.DummyParameters)
+	if err != nil {
+		return nil, err
+	}
+	return rebuilt(n, executor.NewNodeWithType(nil, nil, executor.LITERAL, val, tp)), nil
+}
+
+func isConstant(n *executor.Node) bool {
+	return n == nil || n.Symbol() == executor.LITERAL
+}
@@ -0,0 +1,101 @@
+package compiler
+
+import (
+	"testing"
+)
+
+func buildTree(t *testing.T, exp string) (string, error) {
+	tokens, err := NewScanner(exp).Lexer()
+	if err != nil {
+		t.Fatalf("scan %q: %v", exp, err)
+	}
+	parser := NewParser(tokens)
+	if err = parser.ParseSyntax(); err != nil {
+		t.Fatalf("parse %q: %v", exp, err)
+	}
+	program, err := NewBuilder(parser).Build()
+	if err != nil {
+		return "", err
+	}
+	return program.Root().String(), nil
+}
+
+func TestOptimize(t *testing.T) {
+	cases := []struct {
+		exp  string
+		want string
+	}{
+		{exp: `(1 + 2) * 3 > x`, want: `(> 9 x)`},
+		{exp: `(x)`, want: `x`},
+		{exp: `(x + 1) * 2`, want: `(* (+ x 1) 2)`},
+		{exp: `+x`, want: `(+ x)`},
+		{exp: `+1.5 * x`, want: `(* 1.5 x)`},
+		{exp: `+(x * 2)`, want: `(+ (* x 2))`},
+		{exp: `-(x * 2)`, want: `(- (* x 2))`},
+		{exp: `-2 * 3`, want: `-6`},
+		{exp: `!!flag`, want: `(! (! flag))`},
+		{exp: `!!!flag`, want: `(! flag)`},
+		{exp: `!!(a > b)`, want: `(> a b)`},
+		{exp: `!true`, want: `false`},
+		{exp: `true && x`, want: `(&& true x)`},
+		{exp: `1 > 2 ? x : y`, want: `y`},
+		{exp: `true ? x : 1 / 0`, want: `x`},
+		{exp: `x ? 1 + 1 : y`, want: `(?: x 2 y)`},
+		{exp: `null ?? x`, want: `x`},
+		{exp: `"a" ?? x`, want: `"a"`},
+		{exp: `x ?? 1 + 2`, want: `(?? x 3)`},
+		{exp: `x == null && (null ?? 1) == 1`, want: `(&& (= x null) true)`},
+		{exp: `true && x > 1`, want: `(> x 1)`},
+		{exp: `false || (x == 1)`, want: `(= x 1)`},
+		{exp: `false && x`, want: `false`},
+		{exp: `true || x`, want: `true`},
+		{exp: `false && 1 / 0 > 1`, want: `false`},
+		{exp: `x > 1 && 2 > 1`, want: `(&& (> x 1) true)`},
+		{exp: `"a" + "b" == s`, want: `(= "ab" s)`},
+		{exp: `decimal("0.1") + 0.2 > x`, want: `(> decimal("0.3") x)`},
+		{exp: `decimal(1) / 3 > x`, want: `(> (/ decimal("1") 3) x)`},
+		{exp: `round(decimal("2.5")) + round(2.5)`, want: `(+ round(decimal("2.5")) 3)`},
+	}
+
+	for _, c := range cases {
+		got, err := buildTree(t, c.exp)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %s, want %s", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestOptimize_CompileError(t *testing.T) {
+	for _, exp := range []string{
+		`x > 1 / 0`,
+		`x > 1 + "a"`,
+		`true || 5`,
+		`!!(5)`,
+		`x && (2 > 1) + 1`,
+	} {
+		if got, err := buildTree(t, exp); err == nil {
+			t.Errorf("%q: expected a compile error, got %s", exp, got)
+		}
+	}
+}
+
+func TestBuild_RegexpError(t *testing.T) {
+	cases := []struct {
+		exp string
+		err string
+	}{
+		{exp: `ua =~ "("`, err: "invalid regular expression '(': error parsing regexp: missing closing ): `(` at position 6"},
+		{exp: `a && ua !~ "[a-"`, err: "invalid regular expression '[a-': error parsing regexp: missing closing ]: `[a-` at position 11"},
+		{exp: `ua =~ ("(" + "")`, err: "invalid regular expression '(': error parsing regexp: missing closing ): `(`"},
+	}
+	for _, c := range cases {
+		_, err := buildTree(t, c.exp)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("%q: got error %v, want %q", c.exp, err, c.err)
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package compiler
 
 import (
-	"errors"
 	"fmt"
 	"github.com/qimengxingyuan/young_engine/token"
 )
 
+//go:generate go run ../internal/grammar/gen
+
 type Parser struct {
 	tokens      []token.Token
 	index       int
@@ -19,10 +20,6 @@ func NewParser(tokens []token.Token) *Parser {
 	}
 }
 
-func (p *Parser) rewind() {
-	p.index -= 1
-}
-
 func (p *Parser) next() token.Token {
 	var tok token.Token
 	tok = p.tokens[p.index]
@@ -30,64 +27,117 @@ func (p *Parser) next() token.Token {
 	return tok
 }
 
+// peek returns the next token without advancing the parser, or an Eof token if there is none.
+func (p *Parser) peek() token.Token {
+	if !p.hasNext() {
+		return token.Token{Kind: token.Eof}
+	}
+	return p.tokens[p.index]
+}
+
 func (p *Parser) hasNext() bool {
 	return p.index < p.tokenLength
 }
 
-// checkBalance Checks the balance of tokens which have multiple parts, such as parenthesis.
+// checkBalance Checks the balance of tokens which have multiple parts, such as parenthesis and brackets.
 func (p *Parser) checkBalance() error {
-	var parens int
+	var errs ErrorList
+	var opened []token.Token
 
 	for p.hasNext() {
 		tok := p.next()
-		if tok.Kind == token.OpenParen {
-			parens++
+		if tok.Kind == token.OpenParen || tok.Kind == token.OpenBracket {
+			opened = append(opened, tok)
 			continue
 		}
-		if tok.Kind == token.CloseParen {
-			parens--
+		if tok.Kind == token.CloseParen || tok.Kind == token.CloseBracket {
+			// `([a)`: the brackets opened inside the closed one are not closed, `a)`: the closing token is extra
+			i := len(opened) - 1
+			for i >= 0 && !closes(opened[i].Kind, tok.Kind) {
+				i--
+			}
+			if i < 0 {
+				errs.add(tokenError(SyntaxError, tok, "unbalanced %s", balanceName(tok.Kind)))
+				continue
+			}
+			for _, open := range opened[i+1:] {
+				errs.add(tokenError(SyntaxError, open, "unbalanced %s", balanceName(open.Kind)))
+			}
+			opened = opened[:i]
 			continue
 		}
 	}
 
-	if parens != 0 {
-		return errors.New("unbalanced parenthesis")
+	// the brackets which are not closed
+	for _, tok := range opened {
+		errs.add(tokenError(SyntaxError, tok, "unbalanced %s", balanceName(tok.Kind)))
 	}
 	p.Reset()
-	return nil
+	return errs.Err()
+}
+
+func closes(open, close token.Kind) bool {
+	return open == token.OpenParen && close == token.CloseParen || open == token.OpenBracket && close == token.CloseBracket
+}
+
+func balanceName(k token.Kind) string {
+	if k == token.OpenBracket || k == token.CloseBracket {
+		return "brackets"
+	}
+	return "parenthesis"
 }
 
+// ParseSyntax checks the syntax of the tokens. It recovers from the errors, so that the returned error is an
+// ErrorList of all of them.
 func (p *Parser) ParseSyntax() error {
+	var errs ErrorList
+
 	// '(a + (b > c)' is illegal
-	err := p.checkBalance()
-	if err != nil {
-		return err
-	}
+	errs.add(p.checkBalance())
 
 	// 'param1 + 100 param2' is illegal
 	var lastTok token.Token
+	// the scanner has reported the error of an illegal token, any token may follow it
+	var illegal bool
 	state, err := lastTok.Kind.GetLexerState()
 	for p.hasNext() {
 		tok := p.next()
-		if !state.CanTransitionTo(tok.Kind) {
-			return fmt.Errorf("cannot transition token types from %s [%v] to %s [%v]",
-				lastTok.Kind.String(), lastTok.Value, tok.Kind.String(), tok.Value)
+		switch {
+		case illegal:
+		case tok.Kind.IsIllegal():
+			illegal = true
+			continue
+		case !state.CanTransitionTo(tok.Kind):
+			errs.add(tokenError(SyntaxError, tok, "cannot transition token types from %s [%v] to %s [%v]",
+				lastTok.Kind.String(), lastTok.Value, tok.Kind.String(), tok.Value))
+			tok = p.synchronize(tok)
 		}
 
 		state, err = tok.Kind.GetLexerState()
 		if err != nil {
-			return err
+			errs.add(err)
+			break
 		}
 
-		lastTok = tok
+		lastTok, illegal = tok, tok.Kind.IsIllegal()
 	}
 
 	// 'a + b +' is illegal
-	if !state.IsEOF() {
-		return errors.New("unexpected end of expression")
+	if !state.IsEOF() && !illegal {
+		errs.add(tokenError(SyntaxError, lastTok, "unexpected end of expression"))
 	}
 	p.Reset()
-	return nil
+	errs.sort()
+	return errs.Err()
+}
+
+// synchronize skips the tokens following an unexpected token up to the next operator or parenthesis, where the
+// parsing resumes. It returns the token where it resumes.
+func (p *Parser) synchronize(tok token.Token) token.Token {
+	for !tok.Kind.IsOperator() && !tok.Kind.IsEof() && !tok.Kind.IsIllegal() && p.hasNext() {
+		tok = p.next()
+	}
+	return tok
 }
 
 func (p *Parser) Print() {
@@ -24,3 +24,40 @@ func TestParser_CheckBalance(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestParser_ParseSyntax checks the transitions generated from Engine.g4: the parser accepts the expressions of the
+// grammar, even those which are rejected later for their types.
+func TestParser_ParseSyntax(t *testing.T) {
+	cases := []struct {
+		exp string
+		err string
+	}{
+		{exp: `"a" < "b"`},
+		{exp: `a % -b`},
+		{exp: `a ** -b`},
+		{exp: `"abc"[0] == [1, 2][0]`},
+		{exp: `!1 && (x)`},
+		{exp: `a ?? !b`},
+		{exp: `a - !b`, err: "cannot transition token types from - [45] to ! [!] at position 4"},
+		{exp: `a.(b)`, err: "cannot transition token types from . [46] to ( [40] at position 2"},
+		{exp: `(a) (b)`, err: "cannot transition token types from ) [41] to ( [40] at position 4"},
+	}
+
+	for _, c := range cases {
+		tokens, err := NewScanner(c.exp).Lexer()
+		if err != nil {
+			t.Fatalf("scan %q: %v", c.exp, err)
+		}
+		err = NewParser(tokens).ParseSyntax()
+		if got := errorString(err); got != c.err {
+			t.Errorf("%q: got error %q, want %q", c.exp, got, c.err)
+		}
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -1,12 +1,15 @@
 package compiler
 
 import (
-	"errors"
 	"fmt"
+	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/qimengxingyuan/young_engine/executor"
 	"github.com/qimengxingyuan/young_engine/token"
 )
 
@@ -17,11 +20,19 @@ type Scanner struct {
 	position int    // 遍历规则表达式过程中的位置
 	length   int    // 规则表达式字符串, 用于判断是否扫描结束
 	ch       rune   // position 位置对应的字符
+	lines    []int  // 每一行第一个字符的位置
 }
 
 func NewScanner(source string) *Scanner {
 	runes := []rune(source)
 
+	lines := []int{0}
+	for i, ch := range runes {
+		if ch == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
 	if len(runes) == 0 {
 		runes = append(runes, rune(eofRune))
 	}
@@ -30,9 +41,37 @@ func NewScanner(source string) *Scanner {
 		source: runes,
 		length: len(runes),
 		ch:     runes[0],
+		lines:  lines,
 	}
 }
 
+// lineColumn returns the line and the column of the character at pos, both start at 1.
+func (scanner *Scanner) lineColumn(pos int) (int, int) {
+	line := sort.SearchInts(scanner.lines, pos+1)
+	return line, pos - scanner.lines[line-1] + 1
+}
+
+// errorAt returns a lexical error whose span starts at pos and ends at the current position of the scanner, or
+// covers the character at pos if it is not consumed yet.
+func (scanner *Scanner) errorAt(pos int, format string, args ...interface{}) error {
+	line, column := scanner.lineColumn(pos)
+	span := scanner.position - pos
+	if span < 1 {
+		span = 1
+	}
+	e := &CompileError{
+		Kind:     LexicalError,
+		Message:  fmt.Sprintf(format, args...),
+		Position: pos,
+		Line:     line,
+		Column:   column,
+		span:     span,
+		located:  true,
+	}
+	e.locate(scanner.source[:scanner.length])
+	return e
+}
+
 // read returns the character at the pos of position and advancing
 // the scanner. If the scanner is at Eof, read returns -1.
 func (scanner *Scanner) read() rune {
@@ -69,10 +108,34 @@ func (scanner *Scanner) canRead() bool {
 	return scanner.position < scanner.length
 }
 
-func (scanner *Scanner) skipWhitespace() {
-	for scanner.canRead() && unicode.IsSpace(scanner.cur()) {
-		scanner.read()
+// skipWhitespace skips the whitespaces, the newlines included, and the comments: `// ...` up to the end of the
+// line and `/* ... */`. It returns an error if a block comment is not terminated.
+func (scanner *Scanner) skipWhitespace() error {
+	for scanner.canRead() {
+		switch ch := scanner.cur(); {
+		case unicode.IsSpace(ch):
+			scanner.read()
+		case ch == '/' && scanner.peek() == '/':
+			for scanner.canRead() && scanner.cur() != '\n' {
+				scanner.read()
+			}
+		case ch == '/' && scanner.peek() == '*':
+			startPos := scanner.position
+			scanner.read() // consume /
+			scanner.read() // consume *
+			for scanner.cur() != '*' || scanner.peek() != '/' {
+				if !scanner.canRead() {
+					return scanner.errorAt(startPos, "comment not terminated")
+				}
+				scanner.read()
+			}
+			scanner.read() // consume *
+			scanner.read() // consume /
+		default:
+			return nil
+		}
 	}
+	return nil
 }
 
 func (scanner *Scanner) scanIdentifier() string {
@@ -84,44 +147,214 @@ func (scanner *Scanner) scanIdentifier() string {
 	return string(scanner.source[startPos:scanner.position])
 }
 
-func (scanner *Scanner) scanNumber() string {
+// scanKeyword consumes the next word if it is the given keyword and reports whether it did, otherwise the
+// scanner is left untouched.
+func (scanner *Scanner) scanKeyword(keyword string) bool {
+	position, ch := scanner.position, scanner.ch
+
+	if scanner.skipWhitespace() == nil && isLetter(scanner.cur()) && scanner.scanIdentifier() == keyword {
+		return true
+	}
+
+	scanner.position, scanner.ch = position, ch
+	return false
+}
+
+// scanNumber scans an integer or a float literal:
+//   - decimal integers `123`, hexadecimal `0x1F`, octal `0o17` and binary `0b1010` integers
+//   - floats `123.4`, `.5`, and floats in scientific notation `1e6`, `2.5E-3`
+//   - `_` separates successive digits, `1_000_000`, `0x_FF_FF`
+//
+// A decimal number directly followed by a letter is a duration literal, such as `7d` or `1.5h`. Otherwise a number
+// directly followed by a letter, a digit or a dot, such as `7.7.7` or `0b102`, is an error reporting the position
+// of the offending character.
+func (scanner *Scanner) scanNumber() (interface{}, token.Kind, error) {
 	startPos := scanner.position
-	for isDigit(scanner.peek()) || isDot(scanner.peek()) {
+	base, name := 10, "decimal"
+	if scanner.cur() == '0' {
+		switch lower(scanner.peek()) {
+		case 'x':
+			base, name = 16, "hexadecimal"
+		case 'o':
+			base, name = 8, "octal"
+		case 'b':
+			base, name = 2, "binary"
+		}
+	}
+
+	kind := token.IntegerLiteral
+	if base != 10 {
+		scanner.read() // 0
+		scanner.read() // x o b
+		count, err := scanner.scanDigits(base, true)
+		if err != nil {
+			return nil, kind, err
+		}
+		if count == 0 && !isDigit(scanner.cur()) && !isLetter(scanner.cur()) {
+			return nil, kind, scanner.errorAt(scanner.position, "%s literal has no digits", name)
+		}
+	} else {
+		if _, err := scanner.scanDigits(10, false); err != nil {
+			return nil, kind, err
+		}
+		if isDot(scanner.cur()) {
+			kind = token.FloatLiteral
+			scanner.read()
+			count, err := scanner.scanDigits(10, false)
+			if err != nil {
+				return nil, kind, err
+			}
+			if count == 0 {
+				return nil, kind, scanner.errorAt(scanner.position, "expected a digit after '.'")
+			}
+		}
+		if lower(scanner.cur()) == 'e' {
+			kind = token.FloatLiteral
+			scanner.read()
+			if ch := scanner.cur(); ch == '+' || ch == '-' {
+				scanner.read()
+			}
+			count, err := scanner.scanDigits(10, false)
+			if err != nil {
+				return nil, kind, err
+			}
+			if count == 0 {
+				return nil, kind, scanner.errorAt(scanner.position, "exponent has no digits")
+			}
+		}
+	}
+
+	switch ch := scanner.cur(); {
+	case base == 10 && isLetter(ch) && ch != '_':
+		// 7d 36h 1h30m
+		return scanner.scanDuration(startPos)
+	case base != 10 && (isDigit(ch) || isLetter(ch)):
+		return nil, kind, scanner.errorAt(scanner.position, "invalid digit '%c' in %s literal", ch, name)
+	case isDigit(ch) || isLetter(ch) || isDot(ch):
+		return nil, kind, scanner.errorAt(scanner.position, "unexpected '%c' in numeric literal", ch)
+	}
+
+	literal := string(scanner.source[startPos:scanner.position])
+	digits := strings.ReplaceAll(literal, "_", "")
+	if kind == token.FloatLiteral {
+		val, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return nil, kind, scanner.errorAt(startPos, "float literal '%s' is out of range", literal)
+		}
+		return val, kind, nil
+	}
+
+	if base != 10 {
+		digits = digits[2:]
+	}
+	val, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		// the integers beyond int64, such as the uint64 ids, are decimals without fraction
+		n, _ := new(big.Int).SetString(digits, base)
+		return executor.NewDecimalFromBigInt(n, 0), kind, nil
+	}
+	return val, kind, nil
+}
+
+// scanDuration scans the units and the following numbers of a duration literal starting at startPos, such as
+// `7d`, `36h` or `1h30m`.
+func (scanner *Scanner) scanDuration(startPos int) (interface{}, token.Kind, error) {
+	for isLetter(scanner.cur()) || isDigit(scanner.cur()) || isDot(scanner.cur()) {
 		scanner.read()
 	}
-	scanner.read()
 
-	return string(scanner.source[startPos:scanner.position])
+	literal := string(scanner.source[startPos:scanner.position])
+	val, err := executor.ParseDuration(strings.ReplaceAll(literal, "_", ""))
+	if err != nil {
+		return nil, token.DurationLiteral, scanner.errorAt(startPos, "%v, the units are d h m s ms us ns", err)
+	}
+	return val, token.DurationLiteral, nil
 }
 
+// scanTime scans a time literal, `@` followed by a date `@2024-01-01` or a RFC 3339 time
+// `@2024-01-01T08:30:00+08:00`.
+func (scanner *Scanner) scanTime() (interface{}, error) {
+	startPos := scanner.position
+	scanner.read() // consume @
+	// the `-` after the date is the operator, as in `@2024-01-01-1d`, unless a time follows the date
+	dashes, isTime := 0, false
+	for ch := scanner.cur(); isLetter(ch) || isDigit(ch) || ch == '-' && (isTime || dashes < 2) || ch == ':' ||
+		isDot(ch); ch = scanner.cur() {
+		switch ch {
+		case '-':
+			dashes++
+		case 'T':
+			isTime = true
+		}
+		scanner.read()
+	}
+	// the offset of the time zone, +08:00
+	if scanner.cur() == '+' && isDecimal(scanner.peek()) && strings.ContainsRune(string(scanner.source[startPos:scanner.position]), 'T') {
+		for ch := scanner.cur(); isDecimal(ch) || ch == ':' || ch == '+'; ch = scanner.cur() {
+			scanner.read()
+		}
+	}
+
+	literal := string(scanner.source[startPos+1 : scanner.position])
+	val, err := executor.ParseTime(literal)
+	if err != nil {
+		return nil, scanner.errorAt(startPos, "%v", err)
+	}
+	return val, nil
+}
+
+// scanDigits consumes the digits of the base and the `_` separating them, and returns the number of digits.
+// sepFirst allows a separator before the first digit, right after the prefix of the base like in `0x_FF`.
+func (scanner *Scanner) scanDigits(base int, sepFirst bool) (int, error) {
+	count := 0
+	for {
+		ch := scanner.cur()
+		if ch == '_' {
+			if count == 0 && !sepFirst || digitVal(scanner.peek()) >= base {
+				return count, scanner.errorAt(scanner.position, "'_' must separate successive digits")
+			}
+			scanner.read()
+			continue
+		}
+		if digitVal(ch) >= base {
+			return count, nil
+		}
+		scanner.read()
+		count++
+	}
+}
+
+// scanString scans a string literal quoted by " or ' and returns its value with the escape sequences decoded.
 func (scanner *Scanner) scanString() (string, error) {
 	var err error
-	quote := scanner.read() // consume " or \'
 	startPos := scanner.position
-	endPos := scanner.position
+	quote := scanner.read() // consume " or \'
+	buf := make([]byte, 0)
 	for {
 		ch := scanner.read()
 		if isEof(ch) { // the scanner ends, but the terminator of string literal is not read
-			err = errors.New("string literal not terminated")
+			err = scanner.errorAt(startPos, "string literal not terminated")
 			break
 		}
 		if ch == quote { // read the terminator of string literal
-			endPos = scanner.position - 1 // give up  " or \'
 			break
 		}
 
 		if ch == '\\' { // escape characters
-			if err = scanner.scanEscape(quote); err != nil {
+			if buf, err = scanner.scanEscape(quote, buf); err != nil {
 				break
 			}
+			continue
 		}
+		buf = utf8.AppendRune(buf, ch)
 	}
 
-	return string(scanner.source[startPos:endPos]), err
+	return string(buf), err
 }
 
 func (scanner *Scanner) scanRawString() (string, error) {
 	var err error
+	quotePos := scanner.position
 	quote := scanner.read() // consume `
 	startPos := scanner.position
 	endPos := scanner.position
@@ -129,7 +362,7 @@ func (scanner *Scanner) scanRawString() (string, error) {
 	for {
 		ch := scanner.read()
 		if isEof(ch) {
-			err = errors.New("raw string literal not terminated")
+			err = scanner.errorAt(quotePos, "raw string literal not terminated")
 			break
 		}
 
@@ -144,19 +377,19 @@ func (scanner *Scanner) scanRawString() (string, error) {
 	return string(lit), err
 }
 
-// scanEscape parses an escape sequence where rune is the accepted
-// escaped quote. In case of a syntax error, it stops at the offending
-// character (without consuming it) and returns error message. Otherwise
-// it returns nil.
-func (scanner *Scanner) scanEscape(quote rune) error {
-
+// scanEscape decodes the escape sequence following a backslash and appends it to buf, where rune is the accepted
+// escaped quote. `\xNN` and the octal escapes `\NNN` append a single byte, `\uNNNN` and `\UNNNNNNNN` append the
+// UTF-8 encoding of the code point. In case of a syntax error, it stops at the offending character (without
+// consuming it) and returns error message.
+func (scanner *Scanner) scanEscape(quote rune, buf []byte) ([]byte, error) {
+	startPos := scanner.position - 1 // the backslash is consumed
 	var n int
 	var err error
 	var base, max uint32
-	switch scanner.peek() {
+	switch ch := scanner.cur(); ch {
 	case 'a', 'b', 'f', 'n', 'r', 't', 'v', '\\', '\'', '"', quote:
 		scanner.read()
-		return nil
+		return append(buf, simpleEscapes[ch]...), nil
 	case '0', '1', '2', '3', '4', '5', '6', '7':
 		n, base, max = 3, 8, 255
 	case 'x':
@@ -170,23 +403,23 @@ func (scanner *Scanner) scanEscape(quote rune) error {
 		n, base, max = 8, 16, unicode.MaxRune
 	default:
 		msg := "unknown escape sequence"
-		if scanner.peek() < 0 {
+		if isEof(ch) {
 			msg = "escape sequence not terminated"
 		}
-		err = errors.New(msg)
-		return err
+		err = scanner.errorAt(startPos, "%s", msg)
+		return buf, err
 	}
 
 	var x uint32
 	for n > 0 {
-		d := uint32(digitVal(scanner.peek()))
+		d := uint32(digitVal(scanner.cur()))
 		if d >= base {
-			msg := fmt.Sprintf("illegal character %#U in escape sequence", scanner.peek())
-			if scanner.peek() < 0 {
+			msg := fmt.Sprintf("illegal character %#U in escape sequence", scanner.cur())
+			if isEof(scanner.cur()) {
 				msg = "escape sequence not terminated"
 			}
-			err = errors.New(msg)
-			return err
+			err = scanner.errorAt(startPos, "%s", msg)
+			return buf, err
 		}
 		x = x*base + d
 		scanner.read()
@@ -194,11 +427,14 @@ func (scanner *Scanner) scanEscape(quote rune) error {
 	}
 
 	if x > max || 0xD800 <= x && x < 0xE000 {
-		err = errors.New("escape sequence is invalid Unicode code point")
-		return err
+		err = scanner.errorAt(startPos, "escape sequence is invalid Unicode code point")
+		return buf, err
 	}
 
-	return nil
+	if max == 255 {
+		return append(buf, byte(x)), nil
+	}
+	return utf8.AppendRune(buf, rune(x)), nil
 }
 
 func (scanner *Scanner) scanSwitch2(kid0 token.Kind, ch1 rune, kid1 token.Kind) (string, token.Kind) {
@@ -211,6 +447,20 @@ func (scanner *Scanner) scanSwitch2(kid0 token.Kind, ch1 rune, kid1 token.Kind)
 	return s, kid0
 }
 
+func (scanner *Scanner) scanSwitch3(kid0 token.Kind, ch1 rune, kid1 token.Kind, ch2 rune, kid2 token.Kind) (string, token.Kind) {
+	var s string
+	s += string(scanner.read())
+	switch scanner.cur() {
+	case ch1:
+		s += string(scanner.read())
+		return s, kid1
+	case ch2:
+		s += string(scanner.read())
+		return s, kid2
+	}
+	return s, kid0
+}
+
 func (scanner *Scanner) scanExpect1(kid token.Kind, ch rune) (string, token.Kind) {
 	var s string
 	s += string(scanner.read())
@@ -226,10 +476,13 @@ func (scanner *Scanner) Scan() (token.Token, error) {
 	var tok token.Token
 	var err error
 
-	scanner.skipWhitespace()
+	err = scanner.skipWhitespace()
 	tok.Position = scanner.position
+	tok.Line, tok.Column = scanner.lineColumn(tok.Position)
 
 	switch ch := scanner.cur(); {
+	case err != nil:
+		tok.Kind = token.Illegal
 	case isEof(ch):
 		tok.Kind = token.Eof
 	case isLetter(ch):
@@ -241,24 +494,16 @@ func (scanner *Scanner) Scan() (token.Token, error) {
 		if tok.Kind == token.BoolLiteral {
 			tok.Value = parseBool(literal)
 		}
-	case isDecimal(ch) || isDot(ch): // 123  123.4  .678   7.7.7
-		// Decimal,
-		literal := scanner.scanNumber()
-		if strings.Contains(literal, ".") { // float
-			tok.Value, err = strconv.ParseFloat(literal, 64)
-			tok.Kind = token.FloatLiteral
-		} else { // int
-			tok.Value, err = strconv.ParseInt(literal, 10, 64)
-			tok.Kind = token.IntegerLiteral
-		}
-
-		if err != nil {
-			errorMsg := fmt.Sprintf("Unable to compiler numeric value '%v'", literal)
-			return tok, errors.New(errorMsg)
+		// `not` is only a keyword in front of `in`, otherwise it is a plain variable
+		if literal == "not" && scanner.scanKeyword("in") {
+			tok.Kind = token.NotIn
+			tok.Value = token.NotIn.String()
 		}
+	case isDecimal(ch) || isDot(ch) && isDecimal(scanner.peek()): // 123  123.4  .678   7.7.7
+		tok.Value, tok.Kind, err = scanner.scanNumber()
 	default:
 		switch ch {
-		case '+', '-', '*', '/', '%', '(', ')': // 确定的单一运算符
+		case '+', '-', '/', '%', '(', ')', '[', ']', ',', '.', ':', '^', '~': // 确定的单一运算符
 			tok.Kind = token.LookupOperator(string(ch))
 			tok.Value = scanner.read()
 		case '"', '\'':
@@ -267,50 +512,70 @@ func (scanner *Scanner) Scan() (token.Token, error) {
 		case '`':
 			tok.Kind = token.StringLiteral
 			tok.Value, err = scanner.scanRawString()
+		case '@':
+			tok.Kind = token.TimeLiteral
+			tok.Value, err = scanner.scanTime()
+		case '*':
+			tok.Value, tok.Kind = scanner.scanSwitch2(token.Multiply, '*', token.Power)
+		case '?':
+			tok.Value, tok.Kind = scanner.scanSwitch2(token.Question, '?', token.Coalesce)
 		case '<':
-			tok.Value, tok.Kind = scanner.scanSwitch2(token.LessThan, '=', token.LessEqual)
+			tok.Value, tok.Kind = scanner.scanSwitch3(token.LessThan, '=', token.LessEqual, '<', token.ShiftLeft)
 		case '>':
-			tok.Value, tok.Kind = scanner.scanSwitch2(token.GreaterThan, '=', token.GreaterEqual)
+			tok.Value, tok.Kind = scanner.scanSwitch3(token.GreaterThan, '=', token.GreaterEqual, '>', token.ShiftRight)
 		case '!':
-			tok.Value, tok.Kind = scanner.scanSwitch2(token.Not, '=', token.NotEqual)
+			tok.Value, tok.Kind = scanner.scanSwitch3(token.Not, '=', token.NotEqual, '~', token.NotMatch)
 		case '=':
-			tok.Value, tok.Kind = scanner.scanSwitch2(token.Illegal, '=', token.Equal)
+			tok.Value, tok.Kind = scanner.scanSwitch3(token.Illegal, '=', token.Equal, '~', token.Match)
 			if tok.Kind.IsIllegal() {
-				return tok, errors.New("expected to get '==', but only found '='")
+				err = scanner.errorAt(tok.Position, "expected to get '==' or '=~', but only found '='")
 			}
 		case '&':
-			tok.Value, tok.Kind = scanner.scanSwitch2(token.Illegal, '&', token.And)
-			if tok.Kind.IsIllegal() {
-				return tok, errors.New("expected to get '&&', but only found '&'")
-			}
+			tok.Value, tok.Kind = scanner.scanSwitch2(token.BitAnd, '&', token.And)
 		case '|':
-			tok.Value, tok.Kind = scanner.scanSwitch2(token.Illegal, '|', token.Or)
-			if tok.Kind.IsIllegal() {
-				return tok, errors.New("expected to get '||', but only found '|'")
-			}
+			tok.Value, tok.Kind = scanner.scanSwitch2(token.BitOr, '|', token.Or)
 		default:
 			tok.Kind = token.Illegal
 			tok.Value = string(ch)
-			errMsg := fmt.Sprintf("the scan found an illegal character '%v'", ch)
-			return tok, errors.New(errMsg)
+			err = scanner.errorAt(tok.Position, "the scan found an illegal character '%c'", ch)
 		}
 	}
 
+	tok.Raw = string(scanner.source[tok.Position:scanner.position])
 	return tok, err
 }
 
+// Lexer scans all the tokens of the source up to Eof. It recovers from the lexical errors, so that the returned
+// error is an ErrorList of all of them.
 func (scanner *Scanner) Lexer() ([]token.Token, error) {
 	tokens := make([]token.Token, 0)
 
-	var err error
-	var tok token.Token
+	var errs ErrorList
 	for {
-		tok, err = scanner.Scan()
+		tok, err := scanner.Scan()
+		if err != nil {
+			errs.add(err)
+			scanner.recover(&tok)
+		}
 		tokens = append(tokens, tok)
-		if err != nil || tok.Kind == token.Eof {
+		if tok.Kind == token.Eof {
 			break
 		}
 	}
 
-	return tokens, err
+	return tokens, errs.Err()
+}
+
+// recover skips the rest of the malformed token up to the next whitespace, operator or parenthesis, where the
+// scanning resumes. The token keeps its kind, such as IntegerLiteral for `0x1G`, so that the parser still checks
+// the syntax around it. The parser accepts any token after an Illegal one.
+func (scanner *Scanner) recover(tok *token.Token) {
+	if scanner.position == tok.Position {
+		scanner.read()
+	}
+	for ch := scanner.cur(); scanner.canRead() && !unicode.IsSpace(ch) && !strings.ContainsRune(syncChars, ch); {
+		scanner.read()
+		ch = scanner.cur()
+	}
+	tok.Raw = string(scanner.source[tok.Position:scanner.position])
 }
@@ -2,8 +2,11 @@ package compiler
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/qimengxingyuan/young_engine/executor"
 	"github.com/qimengxingyuan/young_engine/token"
 )
 
@@ -30,3 +33,269 @@ func TestLexer(t *testing.T) {
 
 	fmt.Printf("scanner done\n")
 }
+
+func TestScanner_NotIn(t *testing.T) {
+	cases := []struct {
+		rule  string
+		kinds []token.Kind
+	}{
+		{rule: "uid not in [1, 2]", kinds: []token.Kind{token.Identifier, token.NotIn, token.OpenBracket,
+			token.IntegerLiteral, token.Comma, token.IntegerLiteral, token.CloseBracket, token.Eof}},
+		{rule: "city not\n in ('bj')", kinds: []token.Kind{token.Identifier, token.NotIn, token.OpenParen,
+			token.StringLiteral, token.CloseParen, token.Eof}},
+		{rule: "not && notin", kinds: []token.Kind{token.Identifier, token.And, token.Identifier, token.Eof}},
+		{rule: "not inside", kinds: []token.Kind{token.Identifier, token.Identifier, token.Eof}},
+		{rule: `ua =~ "^Mozilla" && ua !~ x`, kinds: []token.Kind{token.Identifier, token.Match, token.StringLiteral,
+			token.And, token.Identifier, token.NotMatch, token.Identifier, token.Eof}},
+		{rule: "score ?? null ? a : b", kinds: []token.Kind{token.Identifier, token.Coalesce, token.NullLiteral,
+			token.Question, token.Identifier, token.Colon, token.Identifier, token.Eof}},
+		{rule: "a&b|c^~d && x||y", kinds: []token.Kind{token.Identifier, token.BitAnd, token.Identifier, token.BitOr,
+			token.Identifier, token.BitXor, token.BitNot, token.Identifier, token.And, token.Identifier, token.Or,
+			token.Identifier, token.Eof}},
+		{rule: "a<<1 <= b>>2 ** 3 * 4", kinds: []token.Kind{token.Identifier, token.ShiftLeft, token.IntegerLiteral,
+			token.LessEqual, token.Identifier, token.ShiftRight, token.IntegerLiteral, token.Power, token.IntegerLiteral,
+			token.Multiply, token.IntegerLiteral, token.Eof}},
+		{rule: "!ok != !~x", kinds: []token.Kind{token.Not, token.Identifier, token.NotEqual, token.NotMatch,
+			token.Identifier, token.Eof}},
+		{rule: "@2024-01-01-1d", kinds: []token.Kind{token.TimeLiteral, token.Subtraction, token.DurationLiteral,
+			token.Eof}},
+	}
+
+	for _, c := range cases {
+		tokens, err := NewScanner(c.rule).Lexer()
+		if err != nil {
+			t.Errorf("%q: %v", c.rule, err)
+			continue
+		}
+		if len(tokens) != len(c.kinds) {
+			t.Errorf("%q: got %d tokens, want %d", c.rule, len(tokens), len(c.kinds))
+			continue
+		}
+		for i, tok := range tokens {
+			if tok.Kind != c.kinds[i] {
+				t.Errorf("%q: token %d is %v, want %v", c.rule, i, tok.Kind, c.kinds[i])
+			}
+		}
+	}
+}
+
+func TestScanner_Number(t *testing.T) {
+	// the integers beyond int64 are decimals without fraction
+	bigInt := func(s string) executor.Decimal {
+		d, _ := executor.ParseDecimal(s)
+		return d
+	}
+	cases := []struct {
+		rule    string
+		kind    token.Kind
+		value   interface{}
+		wantErr string
+	}{
+		{rule: "123", kind: token.IntegerLiteral, value: int64(123)},
+		{rule: "0123", kind: token.IntegerLiteral, value: int64(123)},
+		{rule: "1_000_000", kind: token.IntegerLiteral, value: int64(1000000)},
+		{rule: "0x1F", kind: token.IntegerLiteral, value: int64(31)},
+		{rule: "0X_ff_ff", kind: token.IntegerLiteral, value: int64(0xffff)},
+		{rule: "0o17", kind: token.IntegerLiteral, value: int64(15)},
+		{rule: "0b1010", kind: token.IntegerLiteral, value: int64(10)},
+		{rule: "0x7fff_ffff_ffff_ffff", kind: token.IntegerLiteral, value: int64(1<<63 - 1)},
+		{rule: "0x8000_0000_0000_0000", kind: token.IntegerLiteral, value: bigInt("9223372036854775808")},
+		{rule: "18446744073709551615", kind: token.IntegerLiteral, value: bigInt("18446744073709551615")},
+		{rule: "123.4", kind: token.FloatLiteral, value: 123.4},
+		{rule: ".5", kind: token.FloatLiteral, value: 0.5},
+		{rule: "1e6", kind: token.FloatLiteral, value: 1e6},
+		{rule: "2.5E-3", kind: token.FloatLiteral, value: 2.5e-3},
+		{rule: ".5e+1", kind: token.FloatLiteral, value: 5.0},
+		{rule: "1_000.000_1", kind: token.FloatLiteral, value: 1000.0001},
+		{rule: "7.7.7", wantErr: "unexpected '.' in numeric literal at position 3"},
+		{rule: "12a", wantErr: "invalid duration '12a', the units are d h m s ms us ns at position 0"},
+		{
+			rule:    "9223372036854775808ns",
+			wantErr: "duration '9223372036854775808ns' is out of range, the units are d h m s ms us ns at position 0",
+		},
+		{rule: "1.5.", wantErr: "unexpected '.' in numeric literal at position 3"},
+		{rule: "1.", wantErr: "expected a digit after '.' at position 2"},
+		{rule: "1e", wantErr: "exponent has no digits at position 2"},
+		{rule: "1e+x", wantErr: "exponent has no digits at position 3"},
+		{rule: "1__0", wantErr: "'_' must separate successive digits at position 1"},
+		{rule: "10_", wantErr: "'_' must separate successive digits at position 2"},
+		{rule: "0x", wantErr: "hexadecimal literal has no digits at position 2"},
+		{rule: "0x1G", wantErr: "invalid digit 'G' in hexadecimal literal at position 3"},
+		{rule: "0o18", wantErr: "invalid digit '8' in octal literal at position 3"},
+		{rule: "0b102", wantErr: "invalid digit '2' in binary literal at position 4"},
+		{rule: "1e400", wantErr: "float literal '1e400' is out of range at position 0"},
+	}
+
+	for _, c := range cases {
+		tok, err := NewScanner(c.rule).Scan()
+		if c.wantErr != "" {
+			if err == nil || err.Error() != c.wantErr {
+				t.Errorf("%q: got error %v, want %q", c.rule, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.rule, err)
+			continue
+		}
+		if tok.Kind != c.kind || !reflect.DeepEqual(tok.Value, c.value) {
+			t.Errorf("%q: got %v %#v, want %v %#v", c.rule, tok.Kind, tok.Value, c.kind, c.value)
+		}
+	}
+}
+
+func TestScanner_String(t *testing.T) {
+	cases := []struct {
+		rule    string
+		value   string
+		wantErr string
+	}{
+		{rule: `'abc\n123'`, value: "abc\n123"},
+		{rule: `"a\tb\\c"`, value: "a\tb\\c"},
+		{rule: `"\a\b\f\r\v"`, value: "\a\b\f\r\v"},
+		{rule: `'it\'s "ok"'`, value: `it's "ok"`},
+		{rule: `"say \"hi\" \'x\'"`, value: `say "hi" 'x'`},
+		{rule: `"\x41\x7a"`, value: "Az"},
+		{rule: `"\101\060"`, value: "A0"},
+		{rule: `"\xff"`, value: "\xff"},
+		{rule: `"中\U0001F600"`, value: "中😀"},
+		{rule: `"\\.com$"`, value: `\.com$`},
+		{rule: "`a\\n\\x`", value: `a\n\x`},
+		{rule: `"\q"`, wantErr: "unknown escape sequence at position 1"},
+		{rule: `"\x4"`, wantErr: "illegal character U+0022 '\"' in escape sequence at position 1"},
+		{rule: `"\400"`, wantErr: "escape sequence is invalid Unicode code point at position 1"},
+		{rule: `"\uD800"`, wantErr: "escape sequence is invalid Unicode code point at position 1"},
+		{rule: `"abc`, wantErr: "string literal not terminated at position 0"},
+		{rule: `"abc\`, wantErr: "escape sequence not terminated at position 4"},
+	}
+
+	for _, c := range cases {
+		tok, err := NewScanner(c.rule).Scan()
+		if c.wantErr != "" {
+			if err == nil || err.Error() != c.wantErr {
+				t.Errorf("%s: got error %v, want %q", c.rule, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.rule, err)
+			continue
+		}
+		if tok.Kind != token.StringLiteral || tok.Value != c.value {
+			t.Errorf("%s: got %v %q, want %q", c.rule, tok.Kind, tok.Value, c.value)
+		}
+		if tok.Raw != c.rule {
+			t.Errorf("%s: got raw %q", c.rule, tok.Raw)
+		}
+	}
+}
+
+func TestScanner_Raw(t *testing.T) {
+	rule := "city not\n in ('bj') && n >= 0x1F"
+	raws := []string{"city", "not\n in", "(", "'bj'", ")", "&&", "n", ">=", "0x1F", ""}
+
+	tokens, err := NewScanner(rule).Lexer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != len(raws) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(raws))
+	}
+	for i, tok := range tokens {
+		if tok.Raw != raws[i] {
+			t.Errorf("token %d: got raw %q, want %q", i, tok.Raw, raws[i])
+		}
+	}
+}
+
+func TestScanner_Time(t *testing.T) {
+	cases := []struct {
+		rule    string
+		kind    token.Kind
+		value   interface{}
+		wantErr string
+	}{
+		{rule: "@2024-01-01", kind: token.TimeLiteral, value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{rule: "@2024-01-01T08:30:00Z", kind: token.TimeLiteral, value: time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{rule: "@2024-01-01T08:30:00.5+08:00", kind: token.TimeLiteral,
+			value: time.Date(2024, 1, 1, 0, 30, 0, 5e8, time.UTC)},
+		{rule: "@2024-01-01T08:30:00-07:00", kind: token.TimeLiteral,
+			value: time.Date(2024, 1, 1, 15, 30, 0, 0, time.UTC)},
+		{rule: "@2024-01-01-1d", kind: token.TimeLiteral, value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{rule: "7d", kind: token.DurationLiteral, value: 7 * 24 * time.Hour},
+		{rule: "36h", kind: token.DurationLiteral, value: 36 * time.Hour},
+		{rule: "1h30m", kind: token.DurationLiteral, value: 90 * time.Minute},
+		{rule: "1.5s", kind: token.DurationLiteral, value: 1500 * time.Millisecond},
+		{rule: "1_000ms", kind: token.DurationLiteral, value: time.Second},
+		{rule: "2us", kind: token.DurationLiteral, value: 2 * time.Microsecond},
+		{rule: "@2024-13-01", wantErr: "invalid time '2024-13-01', expected a date like 2024-01-01 or a RFC 3339 " +
+			"time like 2024-01-01T08:30:00Z at position 0"},
+		{rule: "@2024-01-01T08:30:00", wantErr: "invalid time '2024-01-01T08:30:00', expected a date like " +
+			"2024-01-01 or a RFC 3339 time like 2024-01-01T08:30:00Z at position 0"},
+		{rule: "7x", wantErr: "invalid duration '7x', the units are d h m s ms us ns at position 0"},
+		{rule: "1h30", wantErr: "invalid duration '1h30', the units are d h m s ms us ns at position 0"},
+	}
+
+	for _, c := range cases {
+		tok, err := NewScanner(c.rule).Scan()
+		if c.wantErr != "" {
+			if err == nil || err.Error() != c.wantErr {
+				t.Errorf("%q: got error %v, want %q", c.rule, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.rule, err)
+			continue
+		}
+		if tok.Kind != c.kind {
+			t.Errorf("%q: got %v, want %v", c.rule, tok.Kind, c.kind)
+		}
+		if want, ok := c.value.(time.Time); ok {
+			if !want.Equal(tok.Value.(time.Time)) {
+				t.Errorf("%q: got %v, want %v", c.rule, tok.Value, want)
+			}
+		} else if tok.Value != c.value {
+			t.Errorf("%q: got %v, want %v", c.rule, tok.Value, c.value)
+		}
+	}
+}
+
+func TestScanner_Comment(t *testing.T) {
+	rule := "// the adults of the vip list\n" +
+		"age >= 18 /* the legal age */ &&\n" +
+		"\tuid in [1, /* 2, */ 3] // the end\n"
+	raws := []string{"age", ">=", "18", "&&", "uid", "in", "[", "1", ",", "3", "]", ""}
+	lines := []int{2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 3, 4}
+	columns := []int{1, 5, 8, 31, 2, 6, 9, 10, 11, 22, 23, 1}
+
+	tokens, err := NewScanner(rule).Lexer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != len(raws) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(raws))
+	}
+	for i, tok := range tokens {
+		if tok.Raw != raws[i] || tok.Line != lines[i] || tok.Column != columns[i] {
+			t.Errorf("token %d: got %q at %d:%d, want %q at %d:%d", i, tok.Raw, tok.Line, tok.Column,
+				raws[i], lines[i], columns[i])
+		}
+	}
+
+	cases := []struct {
+		rule    string
+		wantErr string
+	}{
+		{rule: "a > 1 /* never closed", wantErr: "comment not terminated at position 6"},
+		{rule: "a > 1 &&\n  b /* */ #",
+			wantErr: "the scan found an illegal character '#' at position 19 (line 2, column 11)"},
+		{rule: "a > 1 ||\n\n 1.5.", wantErr: "unexpected '.' in numeric literal at position 14 (line 3, column 5)"},
+	}
+	for _, c := range cases {
+		_, err := NewScanner(c.rule).Lexer()
+		if err == nil || err.Error() != c.wantErr {
+			t.Errorf("%q: got error %v, want %q", c.rule, err, c.wantErr)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package executor
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 )
 
 type Node struct {
@@ -12,30 +15,77 @@ type Node struct {
 
 	leftNode, rightNode *Node
 
+	// the items of a LIST node, the arguments of a CALL node, the condition and both branches of a CONDITIONAL node
+	children []*Node
+
+	// the function called by a CALL node
+	function *Function
+
+	// a missing parameter or member read by a VALUE, MEMBER or INDEX node is null rather than an error
+	optional bool
+	// the type expected from a VALUE node by the operator or the function using it, see MissingAsDefault
+	expected TypeFlags
+
 	// the operator that will be used to evaluate this node (such as adding [left] to [right] and return the result)
 	operator operator
 
 	// ensures that both left and right values are appropriate for this node. Returns an error if they aren't operable.
 	typeChecker typeChecker
+
+	// the part of the expression the node is built from, where its runtime errors are located
+	span Span
+	text string
 }
 
-// NewNodeWithPrefixFix The symbol `+` - can represent both unary and binary operators,
-// and the priority of the operator is unique and needs to be adjusted on a case-by-case basis
-// - In abstract syntax tree, if the left subtree of the node is empty and the right subtree is not empty,
-//   it can be judged to be a negative sign. The symbol needs to be corrected
-// - If the right subtree of the right subtree is a symbol other than the prefix symbol [+、-],
-//	  The node order needs to be corrected
-func NewNodeWithPrefixFix(right *Node, symbol Symbol, value interface{}) *Node {
-	needFixed := needFixedSymbol[symbol]
-	if !needFixed {
-		panic("should not use this new node function for current symbol")
+// NewListNode returns a LIST node which evaluates to the list of the values of its items.
+func NewListNode(items []*Node) *Node {
+	node := NewNode(nil, nil, LIST, nil)
+	node.children = items
+	return node
+}
+
+// NewCallNode returns a CALL node which calls the function with the values of the arguments.
+func NewCallNode(fn *Function, args []*Node) (*Node, error) {
+	if err := fn.CheckArity(len(args)); err != nil {
+		return nil, err
 	}
-	if right != nil && right.rightNode != nil && right.symbol != NEGATIVE && right.symbol != POSITIVE {
-		right.leftNode = NewNode(nil, right.leftNode, symbol, value)
-		return right
-	} else {
-		return NewNode(nil, right, symbol, value)
+
+	node := NewNode(nil, nil, CALL, fn.name)
+	node.children = args
+	node.function = fn
+	return node, nil
+}
+
+// NewConditionalNode returns a CONDITIONAL node `cond ? yes : no`. A condition that can never be a boolean and
+// branches whose types can never be compatible are reported at compile time.
+func NewConditionalNode(cond, yes, no *Node) (*Node, error) {
+	if tp, known := cond.StaticType(); known && !tp.IsBool() {
+		return nil, fmt.Errorf(condErrFmt, tp.String())
+	}
+
+	node := NewNode(nil, nil, CONDITIONAL, nil)
+	yesTp, yesKnown := yes.StaticType()
+	noTp, noKnown := no.StaticType()
+	if yesKnown && noKnown && !node.typeChecker(yesTp, noTp) {
+		return nil, CONDITIONAL.formatTypeError(yesTp, noTp)
+	}
+
+	node.children = []*Node{cond, yes, no}
+	return node, nil
+}
+
+// NewMatchNode returns a MATCH or NOTMATCH node. A literal pattern is compiled once here, so an invalid one is
+// reported at compile time, other patterns are compiled when they are evaluated.
+func NewMatchNode(left, right *Node, symbol Symbol) (*Node, error) {
+	node := NewNode(left, right, symbol, nil)
+	if right.symbol == LITERAL && right.tp.IsString() {
+		re, err := compileRegexp(right.value.(string))
+		if err != nil {
+			return nil, err
+		}
+		node.value = re
 	}
+	return node, nil
 }
 
 func NewNode(left, right *Node, symbol Symbol, value interface{}) *Node {
@@ -43,7 +93,7 @@ func NewNode(left, right *Node, symbol Symbol, value interface{}) *Node {
 }
 
 func NewNodeWithType(left, right *Node, symbol Symbol, value interface{}, tp TypeFlags) *Node {
-	return &Node{
+	node := &Node{
 		symbol:      symbol,
 		value:       value,
 		tp:          tp,
@@ -52,6 +102,98 @@ func NewNodeWithType(left, right *Node, symbol Symbol, value interface{}, tp Typ
 		operator:    symbol.getOperator(),
 		typeChecker: symbol.getTypeChecker(),
 	}
+
+	// a missing parameter or member is null on the left of `??` and when it is compared to null
+	switch {
+	case symbol == COALESCE:
+		left.markOptional()
+	case symbol == EQ || symbol == NEQ:
+		if right.isNullLiteral() {
+			left.markOptional()
+		}
+		if left.isNullLiteral() {
+			right.markOptional()
+		}
+	}
+	return node
+}
+
+func (n *Node) Symbol() Symbol {
+	return n.symbol
+}
+
+// Value returns the literal of a LITERAL node or the parameter name of a VALUE node.
+func (n *Node) Value() interface{} {
+	return n.value
+}
+
+// Type returns the type of a LITERAL node.
+func (n *Node) Type() TypeFlags {
+	return n.tp
+}
+
+func (n *Node) Left() *Node {
+	return n.leftNode
+}
+
+func (n *Node) Right() *Node {
+	return n.rightNode
+}
+
+// Children returns the items of a LIST node, the arguments of a CALL node or the condition and the branches of a
+// CONDITIONAL node.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// Function returns the function called by a CALL node.
+func (n *Node) Function() *Function {
+	return n.function
+}
+
+// String returns the tree in prefix notation, such as `(&& (> a 1) true)`.
+func (n *Node) String() string {
+	if n == nil {
+		return "<nil>"
+	}
+
+	switch n.symbol {
+	case LITERAL:
+		if n.tp.IsString() {
+			return fmt.Sprintf("%q", n.value)
+		}
+		if n.tp.IsNull() {
+			return "null"
+		}
+		if n.tp.IsTime() {
+			return "@" + n.value.(time.Time).Format(time.RFC3339Nano)
+		}
+		if n.tp.IsDecimal() {
+			return fmt.Sprintf("decimal(%q)", n.value.(Decimal).String())
+		}
+		return fmt.Sprintf("%v", n.value)
+	case VALUE:
+		return fmt.Sprintf("%v", n.value)
+	case LIST:
+		items := make([]string, len(n.children))
+		for i, child := range n.children {
+			items[i] = child.String()
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	case CALL:
+		args := make([]string, len(n.children))
+		for i, child := range n.children {
+			args[i] = child.String()
+		}
+		return fmt.Sprintf("%v(%s)", n.value, strings.Join(args, ", "))
+	case CONDITIONAL:
+		return fmt.Sprintf("(?: %v %v %v)", n.children[0], n.children[1], n.children[2])
+	}
+
+	if n.leftNode == nil {
+		return fmt.Sprintf("(%v %v)", n.symbol, n.rightNode)
+	}
+	return fmt.Sprintf("(%v %v %v)", n.symbol, n.leftNode, n.rightNode)
 }
 
 func (n *Node) PrintSvg(name string) {
@@ -67,41 +209,245 @@ func (n *Node) PrintSvg(name string) {
 	file.Close()
 }
 
-func (n *Node) Eval(parameters map[string]interface{}) error {
+// Eval evaluates the tree with the given parameters by walking it recursively. The node itself is never
+// modified, every intermediate result lives on the stack of the evaluation.
+func (n *Node) Eval(parameters Parameters) (interface{}, TypeFlags, error) {
 	if n == nil {
-		return nil
+		return nil, TypeNull, nil
+	}
+
+	ret, err := n.eval(newFrame(parameters))
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	return ret.val, ret.tp, nil
+}
+
+func (n *Node) eval(f *frame) (value, error) {
+	ret, err := n.evalNode(f)
+	if err != nil {
+		return value{}, n.locate(err)
+	}
+	return ret, nil
+}
+
+func (n *Node) evalNode(f *frame) (value, error) {
+	switch n.symbol {
+	case AND, OR:
+		return n.evalLogical(f)
+	case LIST:
+		return n.evalList(f)
+	case CALL:
+		return n.evalCall(f)
+	case CONDITIONAL:
+		return n.evalConditional(f)
+	case COALESCE:
+		return n.evalCoalesce(f)
 	}
 
+	var err error
+	var left, right value
 	if n.leftNode != nil {
-		err := n.leftNode.Eval(parameters)
+		left, err = n.leftNode.eval(f)
 		if err != nil {
-			return err
+			return value{}, err
 		}
 	}
 
 	if n.rightNode != nil {
-		err := n.rightNode.Eval(parameters)
+		right, err = n.rightNode.eval(f)
 		if err != nil {
-			return err
+			return value{}, err
 		}
 	}
 
+	return n.apply(left, right, f)
+}
+
+// evalLogical evaluates `&&` and `||` with short-circuit semantics: the right subtree is only
+// evaluated when the left operand does not decide the result on its own.
+func (n *Node) evalLogical(f *frame) (value, error) {
+	left, err := n.leftNode.eval(f)
+	if err != nil {
+		return value{}, err
+	}
+
+	if !left.tp.IsBool() {
+		rightTp, _ := n.rightNode.StaticType()
+		return value{}, n.symbol.formatTypeError(left.tp, rightTp)
+	}
+
+	if (n.symbol == AND && !left.val.(bool)) || (n.symbol == OR && left.val.(bool)) {
+		// the right operand is skipped, but an operand that can never be a boolean is still a type error
+		if rightTp, known := n.rightNode.StaticType(); known && !rightTp.IsBool() {
+			return value{}, n.symbol.formatTypeError(left.tp, rightTp)
+		}
+		return left, nil
+	}
+
+	right, err := n.rightNode.eval(f)
+	if err != nil {
+		return value{}, err
+	}
+
+	return n.apply(left, right, f)
+}
+
+// evalConditional evaluates `cond ? yes : no`, only the branch selected by the condition is evaluated.
+func (n *Node) evalConditional(f *frame) (value, error) {
+	cond, err := n.children[0].eval(f)
+	if err != nil {
+		return value{}, err
+	}
+	if !cond.tp.IsBool() {
+		return value{}, fmt.Errorf(condErrFmt, cond.tp.String())
+	}
+
+	if cond.val.(bool) {
+		return n.children[1].eval(f)
+	}
+	return n.children[2].eval(f)
+}
+
+// evalCoalesce evaluates `x ?? y`, y is only evaluated when x is null.
+func (n *Node) evalCoalesce(f *frame) (value, error) {
+	left, err := n.leftNode.eval(f)
+	if err != nil {
+		return value{}, err
+	}
+	if !left.tp.IsNull() {
+		return left, nil
+	}
+	return n.rightNode.eval(f)
+}
+
+func (n *Node) evalList(f *frame) (value, error) {
+	list := make([]interface{}, len(n.children))
+	for i, child := range n.children {
+		item, err := child.eval(f)
+		if err != nil {
+			return value{}, err
+		}
+		list[i] = item.val
+	}
+	return value{val: list, tp: TypeList}, nil
+}
+
+func (n *Node) evalCall(f *frame) (value, error) {
+	args := make([]value, len(n.children))
+	for i, child := range n.children {
+		arg, err := child.eval(f)
+		if err != nil {
+			return value{}, err
+		}
+		args[i] = arg
+	}
+	return n.function.call(f, args)
+}
+
+// apply checks the types of the evaluated children and runs the operator of the node.
+func (n *Node) apply(left, right value, f *frame) (value, error) {
+	if ret, handled := n.applyNull(left, right); handled {
+		return ret, nil
+	}
+
 	if n.typeChecker != nil {
-		if !n.typeChecker(n.leftNode, n.rightNode) {
-			return n.symbol.formatTypeError(n.leftNode, n.rightNode)
+		if !n.typeChecker(left.tp, right.tp) {
+			return value{}, n.symbol.formatTypeError(left.tp, right.tp)
 		}
 	}
 
-	ret, tp, err := n.operator(n, n.leftNode, n.rightNode, MapParameters(parameters))
+	ret, tp, err := n.operator(n, left, right, f)
 	if err != nil {
-		return err
+		return value{}, err
 	}
-	n.value = ret
-	n.tp = tp
 
-	return nil
+	return value{val: ret, tp: tp}, nil
+}
+
+// Rounds reports whether the result of the node depends on the rounding mode of the program, such as a division
+// of decimals. The mode is only known when the program is evaluated, so the optimizer never folds these nodes.
+func (n *Node) Rounds() bool {
+	switch n.symbol {
+	case DIVIDE, POWER:
+		left, _ := n.leftNode.StaticType()
+		right, _ := n.rightNode.StaticType()
+		return left.IsDecimal() || right.IsDecimal()
+	case CALL:
+		if !n.function.rounds {
+			return false
+		}
+		for _, arg := range n.children {
+			if tp, _ := arg.StaticType(); tp.IsDecimal() {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func (n *Node) GetVal() (interface{}, TypeFlags) {
-	return n.value, n.tp
+// StaticType reports the type produced by the node when it can be determined without evaluating it.
+func (n *Node) StaticType() (TypeFlags, bool) {
+	if n == nil {
+		return TypeNull, false
+	}
+
+	switch n.symbol {
+	case LITERAL:
+		return n.tp, true
+	case POSITIVE, NEGATIVE:
+		return n.rightNode.StaticType()
+	case EQ, NEQ, GT, LT, GTE, LTE, AND, OR, INVERT, IN, NOTIN, MATCH, NOTMATCH:
+		return TypeBool, true
+	case LIST:
+		return TypeList, true
+	case CALL:
+		if n.function.ret.IsSingle() {
+			return n.function.ret, true
+		}
+	case CONDITIONAL:
+		yes, yesKnown := n.children[1].StaticType()
+		no, noKnown := n.children[2].StaticType()
+		if yesKnown && noKnown && yes == no {
+			return yes, true
+		}
+	case COALESCE:
+		left, leftKnown := n.leftNode.StaticType()
+		right, rightKnown := n.rightNode.StaticType()
+		if leftKnown && rightKnown && left == right {
+			return left, true
+		}
+	case BITAND, BITOR, BITXOR, SHIFTLEFT, SHIFTRIGHT, BITNOT:
+		// null operands give null, other types than integer are errors
+		left, leftKnown := n.leftNode.StaticType()
+		right, rightKnown := n.rightNode.StaticType()
+		if (n.leftNode == nil || leftKnown && left.IsInteger()) && rightKnown && right.IsInteger() {
+			return TypeInteger, true
+		}
+	case PLUS, MINUS, MULTIPLY, DIVIDE, MODULUS, POWER:
+		left, leftKnown := n.leftNode.StaticType()
+		right, rightKnown := n.rightNode.StaticType()
+		if !leftKnown || !rightKnown || left.IsNull() || right.IsNull() {
+			return TypeNull, false
+		}
+		if left.IsTemporal() || right.IsTemporal() {
+			return temporalType(n.symbol, left, right)
+		}
+		if n.symbol == PLUS && left.IsString() && right.IsString() {
+			return TypeString, true
+		}
+		if left.IsDecimal() || right.IsDecimal() {
+			if n.typeChecker(left, right) {
+				return TypeDecimal, true
+			}
+			return TypeNull, false
+		}
+		if left == TypeFloat || right == TypeFloat {
+			return TypeFloat, true
+		}
+		if n.symbol != DIVIDE && n.symbol != POWER && left == TypeInteger && right == TypeInteger {
+			return TypeInteger, true
+		}
+	}
+	return TypeNull, false
 }
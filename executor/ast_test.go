@@ -0,0 +1,135 @@
+package executor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/compiler"
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func build(t testing.TB, exp string) (*executor.Program, error) {
+	tokens, err := compiler.NewScanner(exp).Lexer()
+	if err != nil {
+		t.Fatalf("scan %q: %v", exp, err)
+	}
+	parser := compiler.NewParser(tokens)
+	if err = parser.ParseSyntax(); err != nil {
+		t.Fatalf("parse %q: %v", exp, err)
+	}
+	return compiler.NewBuilder(parser).WithSource(exp).Build()
+}
+
+func compile(t testing.TB, exp string) *executor.Program {
+	program, err := build(t, exp)
+	if err != nil {
+		t.Fatalf("build %q: %v", exp, err)
+	}
+	return program
+}
+
+// run compiles and evaluates the expression, errors reported at compile time by the optimizer are returned
+// like evaluation errors.
+func run(t testing.TB, exp string, params map[string]interface{}) (interface{}, executor.TypeFlags, error) {
+	program, err := build(t, exp)
+	if err != nil {
+		return nil, executor.TypeNull, err
+	}
+	return program.Eval(executor.MapParameters(params))
+}
+
+// message returns the message of the error without its location in the expression.
+func message(err error) string {
+	var evalErr *executor.EvalError
+	if errors.As(err, &evalErr) {
+		return evalErr.Err.Error()
+	}
+	return err.Error()
+}
+
+func TestNode_EvalShortCircuit(t *testing.T) {
+	cases := []struct {
+		exp     string
+		params  map[string]interface{}
+		want    bool
+		wantErr bool
+	}{
+		{exp: `x != 0 && 10 / x > 1`, params: map[string]interface{}{"x": 0}, want: false},
+		{exp: `x != 0 && 10 / x > 1`, params: map[string]interface{}{"x": 5}, want: true},
+		{exp: `has_user || user_age > 18`, params: map[string]interface{}{"has_user": true}, want: true},
+		{exp: `has_user || user_age > 18`, params: map[string]interface{}{"has_user": false}, wantErr: true},
+		{exp: `has_user || user_age > 18`, params: map[string]interface{}{"has_user": false, "user_age": 20}, want: true},
+		{exp: `false && missing`, params: nil, want: false},
+		{exp: `true || (missing && 1 / 0 > 1)`, params: nil, want: true},
+		// the skipped operand can never be a boolean
+		{exp: `false && 5`, params: nil, wantErr: true},
+		{exp: `true || 1 + 2`, params: nil, wantErr: true},
+		{exp: `true || -1.5`, params: nil, wantErr: true},
+		// the deciding operand must be a boolean as well
+		{exp: `x && true`, params: map[string]interface{}{"x": 1}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, c.params)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", c.exp)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %v, want %v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestNode_EvalConditional(t *testing.T) {
+	params := map[string]interface{}{"vip": true, "price": 100, "a": false, "b": true, "x": 3, "zero": 0}
+	cases := []struct {
+		exp  string
+		want interface{}
+		err  string
+	}{
+		{exp: `vip ? price * 0.8 : price`, want: 80.0},
+		{exp: `a ? price * 0.8 : price`, want: int64(100)},
+		{exp: `x > 2 ? "big" : "small"`, want: "big"},
+		{exp: `(vip ? 1 : 2) * 10`, want: int64(10)},
+		{exp: `a || b ? x : -x`, want: int64(3)},
+		// right-associative
+		{exp: `a ? 1 : b ? 2 : 3`, want: int64(2)},
+		{exp: `vip ? a ? 1 : 2 : 3`, want: int64(2)},
+		{exp: `a ? 1 : 2 + 5`, want: int64(7)},
+		// only the selected branch is evaluated
+		{exp: `zero == 0 ? 0 : 10 / zero`, want: int64(0)},
+		{exp: `vip ? x : missing`, want: int64(3)},
+		{exp: `a ? missing : "ok"`, want: "ok"},
+		// type errors
+		{exp: `x ? 1 : 2`, err: "type mismatch for operator [?:]: condition='int', expected 'boolean'"},
+		// the constant condition is checked at compile time
+		{exp: `1 ? 2 : 3`, err: "type mismatch for operator [?:]: condition='int', expected 'boolean' at position 0"},
+		{exp: `vip ? 1 : "a"`,
+			err: "type mismatch for operator [?:]: branches 'int' and 'string' are not compatible at position 0"},
+		{exp: `vip ? 1`, err: "expected ':' in conditional expression, but found 'Eof' at position 7"},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.err != "" {
+			if err == nil || message(err) != c.err {
+				t.Errorf("%q: got error %v, want %q", c.exp, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
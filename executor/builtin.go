@@ -0,0 +1,447 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// builtins are the functions available in every expression.
+var builtins = map[string]*Function{}
+
+func init() {
+	for _, fn := range []*Function{
+		// strings
+		{name: "len", params: []TypeFlags{TypeString | TypeList | TypeMap}, ret: TypeInteger, impl: builtinLen},
+		{name: "lower", params: []TypeFlags{TypeString}, ret: TypeString, impl: builtinLower},
+		{name: "upper", params: []TypeFlags{TypeString}, ret: TypeString, impl: builtinUpper},
+		{name: "trim", params: []TypeFlags{TypeString}, ret: TypeString, impl: builtinTrim},
+		{name: "contains", params: []TypeFlags{TypeString, TypeString}, ret: TypeBool, impl: builtinContains},
+		{name: "startsWith", params: []TypeFlags{TypeString, TypeString}, ret: TypeBool, impl: builtinStartsWith},
+		{name: "endsWith", params: []TypeFlags{TypeString, TypeString}, ret: TypeBool, impl: builtinEndsWith},
+		{name: "substr", params: []TypeFlags{TypeString, TypeInteger, TypeInteger}, optional: 1, ret: TypeString,
+			impl: builtinSubstr},
+
+		// math
+		{name: "abs", params: []TypeFlags{TypeNumber}, ret: TypeNumber, impl: builtinAbs},
+		{name: "min", params: []TypeFlags{TypeNumber}, variadic: true, ret: TypeNumber, impl: builtinMin},
+		{name: "max", params: []TypeFlags{TypeNumber}, variadic: true, ret: TypeNumber, impl: builtinMax},
+		{name: "round", params: []TypeFlags{TypeNumber, TypeInteger}, optional: 1, ret: TypeNumber, rounds: true,
+			impl: builtinRound},
+		{name: "floor", params: []TypeFlags{TypeNumber}, ret: TypeNumber, impl: builtinFloor},
+		{name: "ceil", params: []TypeFlags{TypeNumber}, ret: TypeNumber, impl: builtinCeil},
+		{name: "pow", params: []TypeFlags{TypeNumber, TypeNumber}, ret: TypeFloat, impl: builtinPow},
+		{name: "sqrt", params: []TypeFlags{TypeNumber}, ret: TypeFloat, impl: builtinSqrt},
+
+		// type conversion
+		{name: "int", params: []TypeFlags{TypeNumber | TypeString | TypeBool}, ret: TypeInteger, impl: builtinInt},
+		{name: "float", params: []TypeFlags{TypeNumber | TypeString | TypeBool}, ret: TypeFloat, impl: builtinFloat},
+		{name: "string", params: []TypeFlags{TypeNumber | TypeString | TypeBool | TypeTime | TypeDuration},
+			ret: TypeString, impl: builtinString},
+		{name: "bool", params: []TypeFlags{TypeNumber | TypeString | TypeBool}, ret: TypeBool, impl: builtinBool},
+		{name: "decimal", params: []TypeFlags{TypeNumber | TypeString}, ret: TypeDecimal, impl: builtinDecimal},
+
+		// time
+		{name: "time", params: []TypeFlags{TypeString | TypeInteger}, ret: TypeTime, impl: builtinTime},
+		{name: "duration", params: []TypeFlags{TypeString | TypeInteger}, ret: TypeDuration, impl: builtinDuration},
+		{name: "unix", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinUnix},
+		{name: "year", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinYear},
+		{name: "month", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinMonth},
+		{name: "day", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinDay},
+		{name: "hour", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinHour},
+		{name: "minute", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinMinute},
+		{name: "weekday", params: []TypeFlags{TypeTime}, ret: TypeInteger, impl: builtinWeekday},
+	} {
+		fn.pure = true
+		builtins[fn.name] = fn
+	}
+
+	// the calls of impure functions are never folded
+	for _, fn := range []*Function{
+		{name: "now", params: []TypeFlags{}, ret: TypeTime, impl: builtinNow},
+	} {
+		builtins[fn.name] = fn
+	}
+}
+
+// Builtin returns the built-in function of the given name.
+func Builtin(name string) (*Function, bool) {
+	fn, exist := builtins[name]
+	return fn, exist
+}
+
+/*
+* strings
+* */
+
+func builtinLen(f *frame, args []value) (interface{}, TypeFlags, error) {
+	switch v := args[0].val.(type) {
+	case string:
+		return int64(utf8.RuneCountInString(v)), TypeInteger, nil
+	case []interface{}:
+		return int64(len(v)), TypeInteger, nil
+	default:
+		return int64(len(v.(map[string]interface{}))), TypeInteger, nil
+	}
+}
+
+func builtinLower(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return strings.ToLower(args[0].val.(string)), TypeString, nil
+}
+
+func builtinUpper(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return strings.ToUpper(args[0].val.(string)), TypeString, nil
+}
+
+func builtinTrim(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return strings.TrimSpace(args[0].val.(string)), TypeString, nil
+}
+
+func builtinContains(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return strings.Contains(args[0].val.(string), args[1].val.(string)), TypeBool, nil
+}
+
+func builtinStartsWith(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return strings.HasPrefix(args[0].val.(string), args[1].val.(string)), TypeBool, nil
+}
+
+func builtinEndsWith(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return strings.HasSuffix(args[0].val.(string), args[1].val.(string)), TypeBool, nil
+}
+
+// substr(s, start[, length]) returns the characters of s from start, up to length characters.
+func builtinSubstr(f *frame, args []value) (interface{}, TypeFlags, error) {
+	runes := []rune(args[0].val.(string))
+	start := args[1].val.(int64)
+	if start < 0 || start > int64(len(runes)) {
+		return nil, TypeNull, fmt.Errorf("engine: substr start %d out of range [0:%d]", start, len(runes))
+	}
+
+	end := int64(len(runes))
+	if len(args) > 2 {
+		length := args[2].val.(int64)
+		if length < 0 {
+			return nil, TypeNull, fmt.Errorf("engine: substr length %d is negative", length)
+		}
+		// start+length may overflow
+		if length < end-start {
+			end = start + length
+		}
+	}
+	return string(runes[start:end]), TypeString, nil
+}
+
+/*
+* math
+* */
+
+func builtinAbs(f *frame, args []value) (interface{}, TypeFlags, error) {
+	switch v := args[0].val.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, TypeNull, fmt.Errorf("engine: integer overflow: abs(%d)", v)
+		}
+		if v < 0 {
+			return -v, TypeInteger, nil
+		}
+		return v, TypeInteger, nil
+	case Decimal:
+		if v.Sign() < 0 {
+			return v.Neg(), TypeDecimal, nil
+		}
+		return v, TypeDecimal, nil
+	}
+	return math.Abs(args[0].val.(float64)), TypeFloat, nil
+}
+
+func builtinMin(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return extremum(args, LT)
+}
+
+func builtinMax(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return extremum(args, GT)
+}
+
+// extremum returns the argument for which the comparison with all the others holds. The result is a decimal as
+// soon as one of the arguments is a decimal, otherwise a float as soon as one of them is a float.
+func extremum(args []value, cmp Symbol) (interface{}, TypeFlags, error) {
+	ret := args[0]
+	isInt := ret.tp == TypeInteger
+	isDecimal := ret.tp == TypeDecimal
+	for _, arg := range args[1:] {
+		isInt = isInt && arg.tp == TypeInteger
+		isDecimal = isDecimal || arg.tp == TypeDecimal
+		better, _, err := execNumberBinOp(arg, ret, cmp)
+		if err != nil {
+			return nil, TypeNull, err
+		}
+		if better.(bool) {
+			ret = arg
+		}
+	}
+
+	if isInt {
+		return ret.val, TypeInteger, nil
+	}
+	if isDecimal {
+		d, err := toDecimal(ret.val)
+		if err != nil {
+			return nil, TypeNull, err
+		}
+		return d, TypeDecimal, nil
+	}
+	return int2float(ret.val), TypeFloat, nil
+}
+
+// round(x[, digits]) rounds half away from zero, to the given number of decimal digits. A decimal is rounded
+// according to the rounding mode of the program instead.
+func builtinRound(f *frame, args []value) (interface{}, TypeFlags, error) {
+	if args[0].tp == TypeInteger {
+		return args[0].val, TypeInteger, nil
+	}
+	if d, ok := args[0].val.(Decimal); ok {
+		digits := int64(0)
+		if len(args) > 1 {
+			digits = args[1].val.(int64)
+		}
+		if digits > maxDecimalScale || digits < -maxDecimalScale {
+			return nil, TypeNull, fmt.Errorf("engine: round to %d digits is out of range", digits)
+		}
+		return d.Round(int32(digits), f.rounding), TypeDecimal, nil
+	}
+
+	v := args[0].val.(float64)
+	if len(args) == 1 {
+		return math.Round(v), TypeFloat, nil
+	}
+	digits := args[1].val.(int64)
+	if digits > maxFloatDigits || digits < -maxFloatDigits {
+		return nil, TypeNull, fmt.Errorf("engine: round to %d digits is out of range", digits)
+	}
+	scale := math.Pow(10, float64(digits))
+	if math.IsInf(v*scale, 0) {
+		// a float has no digit at the scale
+		return v, TypeFloat, nil
+	}
+	return math.Round(v*scale) / scale, TypeFloat, nil
+}
+
+// maxFloatDigits bounds the digits of round for a float, 10 raised to a power beyond it is not a finite float.
+const maxFloatDigits = 308
+
+func builtinFloor(f *frame, args []value) (interface{}, TypeFlags, error) {
+	if args[0].tp == TypeInteger {
+		return args[0].val, TypeInteger, nil
+	}
+	if d, ok := args[0].val.(Decimal); ok {
+		return d.Round(0, RoundFloor), TypeDecimal, nil
+	}
+	return math.Floor(args[0].val.(float64)), TypeFloat, nil
+}
+
+func builtinCeil(f *frame, args []value) (interface{}, TypeFlags, error) {
+	if args[0].tp == TypeInteger {
+		return args[0].val, TypeInteger, nil
+	}
+	if d, ok := args[0].val.(Decimal); ok {
+		return d.Round(0, RoundCeiling), TypeDecimal, nil
+	}
+	return math.Ceil(args[0].val.(float64)), TypeFloat, nil
+}
+
+func builtinPow(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return math.Pow(int2float(args[0].val), int2float(args[1].val)), TypeFloat, nil
+}
+
+func builtinSqrt(f *frame, args []value) (interface{}, TypeFlags, error) {
+	v := int2float(args[0].val)
+	if v < 0 {
+		return nil, TypeNull, fmt.Errorf("engine: square root of negative number %v", v)
+	}
+	return math.Sqrt(v), TypeFloat, nil
+}
+
+/*
+* type conversion
+* */
+
+func builtinInt(f *frame, args []value) (interface{}, TypeFlags, error) {
+	switch v := args[0].val.(type) {
+	case int64:
+		return v, TypeInteger, nil
+	case float64:
+		return floatToInt(v)
+	case Decimal:
+		i := v.Round(0, RoundDown).int()
+		if !i.IsInt64() {
+			return nil, TypeNull, fmt.Errorf("engine: %v is out of the range of int", v)
+		}
+		return i.Int64(), TypeInteger, nil
+	case bool:
+		if v {
+			return int64(1), TypeInteger, nil
+		}
+		return int64(0), TypeInteger, nil
+	default:
+		s := strings.TrimSpace(v.(string))
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, TypeInteger, nil
+		}
+		if fv, err := strconv.ParseFloat(s, 64); err == nil {
+			return floatToInt(fv)
+		}
+		return nil, TypeNull, fmt.Errorf("engine: cannot convert '%s' to int", s)
+	}
+}
+
+func floatToInt(v float64) (interface{}, TypeFlags, error) {
+	if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+		return nil, TypeNull, fmt.Errorf("engine: %v is out of the range of int", v)
+	}
+	return int64(v), TypeInteger, nil
+}
+
+func builtinFloat(f *frame, args []value) (interface{}, TypeFlags, error) {
+	switch v := args[0].val.(type) {
+	case int64, float64, Decimal:
+		return int2float(v), TypeFloat, nil
+	case bool:
+		if v {
+			return 1.0, TypeFloat, nil
+		}
+		return 0.0, TypeFloat, nil
+	default:
+		s := strings.TrimSpace(v.(string))
+		fv, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, TypeNull, fmt.Errorf("engine: cannot convert '%s' to float", s)
+		}
+		return fv, TypeFloat, nil
+	}
+}
+
+func builtinString(f *frame, args []value) (interface{}, TypeFlags, error) {
+	switch v := args[0].val.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), TypeString, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), TypeString, nil
+	case bool:
+		return strconv.FormatBool(v), TypeString, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), TypeString, nil
+	case time.Duration:
+		return v.String(), TypeString, nil
+	case Decimal:
+		return v.String(), TypeString, nil
+	default:
+		return v, TypeString, nil
+	}
+}
+
+func builtinBool(f *frame, args []value) (interface{}, TypeFlags, error) {
+	switch v := args[0].val.(type) {
+	case int64:
+		return v != 0, TypeBool, nil
+	case float64:
+		return v != 0, TypeBool, nil
+	case Decimal:
+		return v.Sign() != 0, TypeBool, nil
+	case bool:
+		return v, TypeBool, nil
+	default:
+		s := strings.TrimSpace(v.(string))
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, TypeNull, fmt.Errorf("engine: cannot convert '%s' to boolean", s)
+		}
+		return b, TypeBool, nil
+	}
+}
+
+// decimal(x) converts a number, a float by the shortest decimal which reads back as the same float, or parses a
+// string such as "12.34" digit by digit.
+func builtinDecimal(f *frame, args []value) (interface{}, TypeFlags, error) {
+	if s, ok := args[0].val.(string); ok {
+		d, err := ParseDecimal(strings.TrimSpace(s))
+		if err != nil {
+			return nil, TypeNull, fmt.Errorf("engine: %v", err)
+		}
+		return d, TypeDecimal, nil
+	}
+	d, err := toDecimal(args[0].val)
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	return d, TypeDecimal, nil
+}
+
+/*
+* time
+* */
+
+// now() returns the time of the evaluation, read from the clock of the program.
+func builtinNow(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return f.currentTime(), TypeTime, nil
+}
+
+// time(x) parses a date or a RFC 3339 time, or converts a unix timestamp in seconds.
+func builtinTime(f *frame, args []value) (interface{}, TypeFlags, error) {
+	if sec, ok := args[0].val.(int64); ok {
+		return time.Unix(sec, 0).UTC(), TypeTime, nil
+	}
+	t, err := ParseTime(strings.TrimSpace(args[0].val.(string)))
+	if err != nil {
+		return nil, TypeNull, fmt.Errorf("engine: %v", err)
+	}
+	return t, TypeTime, nil
+}
+
+// duration(x) parses a duration such as "36h" or "1h30m", or converts a number of seconds.
+func builtinDuration(f *frame, args []value) (interface{}, TypeFlags, error) {
+	if sec, ok := args[0].val.(int64); ok {
+		d, ok := mulInt(sec, int64(time.Second))
+		if !ok {
+			return nil, TypeNull, fmt.Errorf("engine: duration(%d) is out of range", sec)
+		}
+		return time.Duration(d), TypeDuration, nil
+	}
+	d, err := ParseDuration(strings.TrimSpace(args[0].val.(string)))
+	if err != nil {
+		return nil, TypeNull, fmt.Errorf("engine: %v", err)
+	}
+	return d, TypeDuration, nil
+}
+
+func builtinUnix(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return args[0].val.(time.Time).Unix(), TypeInteger, nil
+}
+
+// the fields of a time are the ones of its own location
+func builtinYear(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return int64(args[0].val.(time.Time).Year()), TypeInteger, nil
+}
+
+func builtinMonth(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return int64(args[0].val.(time.Time).Month()), TypeInteger, nil
+}
+
+func builtinDay(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return int64(args[0].val.(time.Time).Day()), TypeInteger, nil
+}
+
+func builtinHour(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return int64(args[0].val.(time.Time).Hour()), TypeInteger, nil
+}
+
+func builtinMinute(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return int64(args[0].val.(time.Time).Minute()), TypeInteger, nil
+}
+
+// weekday(t) returns the day of the week of t, 0 for Sunday to 6 for Saturday.
+func builtinWeekday(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return int64(args[0].val.(time.Time).Weekday()), TypeInteger, nil
+}
@@ -0,0 +1,110 @@
+package executor_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func TestBuiltin(t *testing.T) {
+	params := map[string]interface{}{
+		"name": "  Young Engine  ", "city": "上海", "tags": []string{"a", "b"},
+		"user": map[string]interface{}{"age": 20}, "x": -3, "y": 2.5, "s": "42",
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		wantErr bool
+	}{
+		// strings
+		{exp: `len(city)`, want: int64(2)},
+		{exp: `len(tags) + len(user)`, want: int64(3)},
+		{exp: `lower(trim(name))`, want: "young engine"},
+		{exp: `upper("abc") == "ABC"`, want: true},
+		{exp: `contains(name, "Eng") && startsWith(trim(name), "You") && endsWith(name, "  ")`, want: true},
+		{exp: `substr("hello", 1, 3)`, want: "ell"},
+		{exp: `substr("hello", 3)`, want: "lo"},
+		{exp: `substr("hello", 3, 10)`, want: "lo"},
+		{exp: `substr("hello", 1, 9223372036854775807)`, want: "ello"},
+		{exp: `substr(city, 1)`, want: "海"},
+		{exp: `substr("hello", 6)`, wantErr: true},
+		{exp: `len(x)`, wantErr: true},
+
+		// math
+		{exp: `abs(x)`, want: int64(3)},
+		{exp: `abs(-y)`, want: 2.5},
+		{exp: `min(3, x, 7)`, want: int64(-3)},
+		{exp: `max(x, y)`, want: 2.5},
+		{exp: `max(1, 2, 3.0)`, want: 3.0},
+		{exp: `round(y)`, want: 3.0},
+		{exp: `round(3.14159, 2)`, want: 3.14},
+		{exp: `round(1234.5, -2)`, want: 1200.0},
+		{exp: `round(2.5, -308)`, want: 0.0},
+		{exp: `round(1e300, 300)`, want: 1e300},
+		{exp: `round(1.5, 9223372036854775807)`, wantErr: true},
+		{exp: `round(2.5, -400)`, wantErr: true},
+		{exp: `round(x)`, want: int64(-3)},
+		{exp: `floor(y) + ceil(y)`, want: 5.0},
+		{exp: `pow(2, 10)`, want: 1024.0},
+		{exp: `sqrt(16)`, want: 4.0},
+		{exp: `sqrt(x)`, wantErr: true},
+		{exp: `-abs(x) * 2`, want: int64(-6)},
+
+		// type conversion
+		{exp: `int(s) + 1`, want: int64(43)},
+		{exp: `int(y)`, want: int64(2)},
+		{exp: `int("2.9")`, want: int64(2)},
+		{exp: `int(true)`, want: int64(1)},
+		{exp: `int("abc")`, wantErr: true},
+		{exp: `float(s)`, want: 42.0},
+		{exp: `string(x) + string(y) + string(false)`, want: "-32.5false"},
+		{exp: `bool("true") && bool(1) && !bool(0.0)`, want: true},
+		{exp: `bool("yes")`, wantErr: true},
+		{exp: `string(tags)`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestBuiltin_CompileError(t *testing.T) {
+	for _, exp := range []string{
+		`unknown(1)`,
+		`len()`,
+		`len(name, 1)`,
+		`substr(name)`,
+		`min()`,
+		`len(5)`,
+		`upper(1 + 2)`,
+	} {
+		if _, err := build(t, exp); err == nil {
+			t.Errorf("%q: expected a compile error", exp)
+		}
+	}
+}
+
+func TestBuiltin_Fold(t *testing.T) {
+	program := compile(t, `x > max(1, 2) + len("abc")`)
+	if got := program.Root().String(); got != "(> x 5)" {
+		t.Errorf("got %s, want (> x 5)", got)
+	}
+	if got, _, _ := program.Eval(executor.MapParameters{"x": 6}); got != true {
+		t.Errorf("got %v, want true", got)
+	}
+}
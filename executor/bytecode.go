@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// opcode is the operation of a single instruction of the virtual machine.
+type opcode uint8
+
+const (
+	opLoadConst   opcode = iota // push the literal held by the node
+	opLoadParam                 // push the parameter named by the node
+	opUnary                     // pop the operand, push the result of the unary operator of the node
+	opBinary                    // pop both operands, push the result of the binary operator of the node
+	opJumpIfFalse               // `&&`: if the boolean on top of the stack is false, jump to arg and keep it as result
+	opJumpIfTrue                // `||`: if the boolean on top of the stack is true, jump to arg and keep it as result
+	opLogical                   // the right operand of `&&` `||` on top of the stack must be a boolean
+	opMakeList                  // pop arg items, push the list made of them
+	opCall                      // pop arg arguments, push the result of the function called by the node
+	opBranch                    // `?:`: pop the boolean condition, jump to arg if it is false
+	opJump                      // jump to arg
+	opCoalesce                  // `??`: if the value on top of the stack is not null, jump to arg and keep it as result
+)
+
+var opcodeNames = [...]string{
+	opLoadConst:   "LOAD_CONST",
+	opLoadParam:   "LOAD_PARAM",
+	opUnary:       "UNARY",
+	opBinary:      "BINARY",
+	opJumpIfFalse: "JUMP_IF_FALSE",
+	opJumpIfTrue:  "JUMP_IF_TRUE",
+	opLogical:     "LOGICAL",
+	opMakeList:    "MAKE_LIST",
+	opCall:        "CALL",
+	opBranch:      "BRANCH",
+	opJump:        "JUMP",
+	opCoalesce:    "COALESCE",
+}
+
+func (op opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("opcode(%d)", op)
+}
+
+type instruction struct {
+	op   opcode
+	arg  int   // jump target, number of items of a list or arguments of a call
+	node *Node // the node the instruction was lowered from
+
+	// the static type of the skipped operand of a jump, if it is known
+	skipped      TypeFlags
+	skippedKnown bool
+}
+
+func (ins instruction) String() string {
+	switch ins.op {
+	case opLoadConst, opLoadParam:
+		return fmt.Sprintf("%-14s %v", ins.op, ins.node.value)
+	case opCall:
+		return fmt.Sprintf("%-14s %v %d", ins.op, ins.node.value, ins.arg)
+	case opJumpIfFalse, opJumpIfTrue, opMakeList, opBranch, opJump, opCoalesce:
+		return fmt.Sprintf("%-14s %d", ins.op, ins.arg)
+	default:
+		return fmt.Sprintf("%-14s %v", ins.op, ins.node.symbol)
+	}
+}
+
+// emitter lowers an abstract syntax tree into a flat stream of instructions for the virtual machine.
+type emitter struct {
+	code     []instruction
+	depth    int // depth of the stack after the instructions emitted so far
+	maxDepth int
+}
+
+func (e *emitter) emit(ins instruction, delta int) int {
+	e.code = append(e.code, ins)
+	e.depth += delta
+	if e.depth > e.maxDepth {
+		e.maxDepth = e.depth
+	}
+	return len(e.code) - 1
+}
+
+func (e *emitter) lower(n *Node) error {
+	if n == nil {
+		return errors.New("engine: missing operand")
+	}
+
+	switch n.symbol {
+	case LITERAL:
+		e.emit(instruction{op: opLoadConst, node: n}, 1)
+	case VALUE:
+		e.emit(instruction{op: opLoadParam, node: n}, 1)
+	case POSITIVE, NEGATIVE, INVERT, BITNOT:
+		if err := e.lower(n.rightNode); err != nil {
+			return err
+		}
+		e.emit(instruction{op: opUnary, node: n}, 0)
+	case AND, OR:
+		if err := e.lower(n.leftNode); err != nil {
+			return err
+		}
+		op := opJumpIfFalse
+		if n.symbol == OR {
+			op = opJumpIfTrue
+		}
+		skipped, known := n.rightNode.StaticType()
+		jump := e.emit(instruction{op: op, node: n, skipped: skipped, skippedKnown: known}, -1)
+		if err := e.lower(n.rightNode); err != nil {
+			return err
+		}
+		e.emit(instruction{op: opLogical, node: n}, 0)
+		e.code[jump].arg = len(e.code)
+	case CONDITIONAL:
+		if err := e.lower(n.children[0]); err != nil {
+			return err
+		}
+		branch := e.emit(instruction{op: opBranch, node: n}, -1)
+		if err := e.lower(n.children[1]); err != nil {
+			return err
+		}
+		// the result of the first branch is replaced by the one of the second branch when it is selected
+		jump := e.emit(instruction{op: opJump, node: n}, -1)
+		e.code[branch].arg = len(e.code)
+		if err := e.lower(n.children[2]); err != nil {
+			return err
+		}
+		e.code[jump].arg = len(e.code)
+	case COALESCE:
+		if err := e.lower(n.leftNode); err != nil {
+			return err
+		}
+		jump := e.emit(instruction{op: opCoalesce, node: n}, -1)
+		if err := e.lower(n.rightNode); err != nil {
+			return err
+		}
+		e.code[jump].arg = len(e.code)
+	case LIST:
+		for _, child := range n.children {
+			if err := e.lower(child); err != nil {
+				return err
+			}
+		}
+		e.emit(instruction{op: opMakeList, arg: len(n.children), node: n}, 1-len(n.children))
+	case CALL:
+		for _, child := range n.children {
+			if err := e.lower(child); err != nil {
+				return err
+			}
+		}
+		e.emit(instruction{op: opCall, arg: len(n.children), node: n}, 1-len(n.children))
+	default:
+		if n.operator == nil {
+			return fmt.Errorf("engine: no operator for symbol '%v'", n.symbol)
+		}
+		if err := e.lower(n.leftNode); err != nil {
+			return err
+		}
+		if err := e.lower(n.rightNode); err != nil {
+			return err
+		}
+		e.emit(instruction{op: opBinary, node: n}, -1)
+	}
+
+	return nil
+}
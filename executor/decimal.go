@@ -0,0 +1,419 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number, the unscaled integer times ten to the power of -scale, e.g. 12.34 is 1234
+// with a scale of 2. Decimals are immutable, the zero value is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// DivisionScale is the number of decimal digits kept by a division of decimals whose quotient does not
+// terminate, the last digit is rounded according to the rounding mode of the program.
+const DivisionScale = 16
+
+// maxDecimalScale bounds the scale of the result of an operator, so that `**` can not exhaust the memory.
+const maxDecimalScale = 1 << 16
+
+// RoundingMode decides how a decimal is rounded to a number of digits, by `/`, `**` with a negative exponent and
+// `round()`.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds to the nearest neighbour, and away from zero when both neighbours are equidistant:
+	// 2.5 is 3 and -2.5 is -3. It is the rounding of `round()` on floats.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds to the nearest neighbour, and to the even one when both neighbours are equidistant:
+	// 2.5 is 2 and 3.5 is 4, also known as the banker's rounding.
+	RoundHalfEven
+	// RoundHalfDown rounds to the nearest neighbour, and toward zero when both neighbours are equidistant
+	RoundHalfDown
+	// RoundUp rounds away from zero
+	RoundUp
+	// RoundDown rounds toward zero, it truncates
+	RoundDown
+	// RoundCeiling rounds toward positive infinity
+	RoundCeiling
+	// RoundFloor rounds toward negative infinity
+	RoundFloor
+)
+
+var roundingModeNames = [...]string{
+	RoundHalfUp:   "half_up",
+	RoundHalfEven: "half_even",
+	RoundHalfDown: "half_down",
+	RoundUp:       "up",
+	RoundDown:     "down",
+	RoundCeiling:  "ceiling",
+	RoundFloor:    "floor",
+}
+
+func (m RoundingMode) String() string {
+	if m >= 0 && int(m) < len(roundingModeNames) {
+		return roundingModeNames[m]
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// ParseRoundingMode returns the rounding mode of the given name, such as "half_up" or "half_even". An empty name
+// is RoundHalfUp.
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	if name == "" {
+		return RoundHalfUp, nil
+	}
+	for mode, modeName := range roundingModeNames {
+		if modeName == name {
+			return RoundingMode(mode), nil
+		}
+	}
+	return RoundHalfUp, fmt.Errorf("engine: unknown rounding mode '%s'", name)
+}
+
+var bigTen = big.NewInt(10)
+
+// pow10 returns ten to the power of the non-negative n.
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(n), nil)
+}
+
+// NewDecimal returns the decimal unscaled times ten to the power of -scale, NewDecimal(1234, 2) is 12.34.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// NewDecimalFromBigInt returns the decimal unscaled times ten to the power of -scale, unscaled is copied.
+func NewDecimalFromBigInt(unscaled *big.Int, scale int32) Decimal {
+	return Decimal{unscaled: new(big.Int).Set(unscaled), scale: scale}
+}
+
+// bigInteger returns the integer as an int64 when it fits, otherwise as a decimal without fraction, so that the
+// integers of any size, such as the uint64 ids, are exact.
+func bigInteger(i *big.Int) interface{} {
+	if i.IsInt64() {
+		return i.Int64()
+	}
+	return NewDecimalFromBigInt(i, 0)
+}
+
+// ParseDecimal parses a decimal number such as `12.34`, `-0.5` or `1.5e3`, digit by digit without going through
+// a float.
+func ParseDecimal(s string) (Decimal, error) {
+	invalid := fmt.Errorf("invalid decimal '%s'", s)
+
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, invalid
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	sign := ""
+	if mantissa != "" && (mantissa[0] == '-' || mantissa[0] == '+') {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	intPart, fracPart := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		intPart, fracPart = mantissa[:i], mantissa[i+1:]
+	}
+	digits := intPart + fracPart
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return Decimal{}, invalid
+	}
+
+	unscaled, _ := new(big.Int).SetString(sign+digits, 10)
+	scale := int64(len(fracPart)) - exp
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		return Decimal{}, fmt.Errorf("decimal '%s' is out of range", s)
+	}
+	if scale < 0 {
+		return Decimal{unscaled: unscaled.Mul(unscaled, pow10(-scale))}, nil
+	}
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+// decimalFromFloat converts the float into the shortest decimal which reads back as the same float, 0.1 is
+// exactly 0.1.
+func decimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("engine: %v can not be converted to decimal", f)
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// toDecimal promotes an integer, a float or a decimal into a decimal.
+func toDecimal(n interface{}) (Decimal, error) {
+	switch v := n.(type) {
+	case int64:
+		return NewDecimal(v, 0), nil
+	case float64:
+		return decimalFromFloat(v)
+	case Decimal:
+		return v, nil
+	default:
+		msg := fmt.Sprintf("unreachable code:%v", v)
+		panic(msg)
+	}
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// String returns the decimal with all the digits of its scale, such as `12.30`.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON renders the decimal as a JSON number with all of its digits.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Float64 returns the float nearest to d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// rescale returns the unscaled integer of d at the given scale, which is not less than the scale of d.
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.int()
+	}
+	return new(big.Int).Mul(d.int(), pow10(int64(scale-d.scale)))
+}
+
+// align returns the unscaled integers of both decimals at the larger of their scales.
+func align(d1, d2 Decimal) (*big.Int, *big.Int, int32) {
+	scale := d1.scale
+	if d2.scale > scale {
+		scale = d2.scale
+	}
+	return d1.rescale(scale), d2.rescale(scale), scale
+}
+
+// Cmp compares d and other and returns -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	u1, u2, _ := align(d, other)
+	return u1.Cmp(u2)
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	u1, u2, scale := align(d, other)
+	return Decimal{unscaled: new(big.Int).Add(u1, u2), scale: scale}
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	u1, u2, scale := align(d, other)
+	return Decimal{unscaled: new(big.Int).Sub(u1, u2), scale: scale}
+}
+
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), other.int()), scale: d.scale + other.scale}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Quo returns d divided by other. The quotient is exact when it terminates within DivisionScale digits, otherwise
+// its last digit is rounded according to the mode. The trailing zeros of the quotient are removed.
+func (d Decimal) Quo(other Decimal, mode RoundingMode) (Decimal, error) {
+	if other.Sign() == 0 {
+		return Decimal{}, divideZeroErr
+	}
+
+	scale := int32(DivisionScale)
+	if d.scale > scale {
+		scale = d.scale
+	}
+	// d / other at the scale is d.unscaled * 10^(scale - d.scale + other.scale) / other.unscaled
+	num := new(big.Int).Mul(d.int(), pow10(int64(scale-d.scale+other.scale)))
+	q := Decimal{unscaled: roundQuo(num, other.int(), mode), scale: scale}
+	return q.trim(), nil
+}
+
+// Rem returns the remainder of d divided by other truncated toward zero, it has the sign of d.
+func (d Decimal) Rem(other Decimal) (Decimal, error) {
+	if other.Sign() == 0 {
+		return Decimal{}, divideZeroErr
+	}
+	u1, u2, scale := align(d, other)
+	return Decimal{unscaled: new(big.Int).Rem(u1, u2), scale: scale}, nil
+}
+
+// Pow returns d to the power of the integer exp, a negative exponent divides 1 by d to the power of -exp.
+func (d Decimal) Pow(exp int64, mode RoundingMode) (Decimal, error) {
+	// the absolute value of math.MinInt64 does not fit in int64
+	if exp == math.MinInt64 {
+		return Decimal{}, fmt.Errorf("engine: decimal %v to the power of %d is out of range", d, exp)
+	}
+	n := exp
+	if n < 0 {
+		n = -n
+	}
+	// the digits of the result grow with the exponent, the bounds are divided rather than n multiplied so that
+	// they do not overflow
+	bits := int64(d.int().BitLen() - 1)
+	if d.scale > 0 && n > maxDecimalScale/int64(d.scale) || bits > 0 && n > 4*maxDecimalScale/bits {
+		return Decimal{}, fmt.Errorf("engine: decimal %v to the power of %d is out of range", d, exp)
+	}
+
+	ret := Decimal{unscaled: new(big.Int).Exp(d.int(), big.NewInt(n), nil), scale: d.scale * int32(n)}
+	if exp < 0 {
+		return NewDecimal(1, 0).Quo(ret, mode)
+	}
+	return ret, nil
+}
+
+// Round returns d rounded to the given number of digits after the decimal point according to the mode, a
+// negative number of digits rounds to tens, hundreds and so on. d is returned as it is when it has fewer digits.
+func (d Decimal) Round(digits int32, mode RoundingMode) Decimal {
+	if d.scale <= digits {
+		return d
+	}
+	if digits >= 0 {
+		return Decimal{unscaled: roundQuo(d.int(), pow10(int64(d.scale-digits)), mode), scale: digits}
+	}
+	q := roundQuo(d.int(), pow10(int64(d.scale)-int64(digits)), mode)
+	return Decimal{unscaled: q.Mul(q, pow10(-int64(digits)))}
+}
+
+// trim removes the trailing zeros after the decimal point.
+func (d Decimal) trim() Decimal {
+	u, scale := new(big.Int).Set(d.int()), d.scale
+	r := new(big.Int)
+	for scale > 0 {
+		q, _ := new(big.Int).QuoRem(u, bigTen, r)
+		if r.Sign() != 0 {
+			break
+		}
+		u, scale = q, scale-1
+	}
+	return Decimal{unscaled: u, scale: scale}
+}
+
+// roundQuo returns num divided by den, rounded to an integer according to the mode.
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// the sign of the exact quotient, q is truncated toward zero
+	sign := int64(num.Sign() * den.Sign())
+	// compare the remainder with the half of the divisor
+	twice := new(big.Int).Abs(r)
+	half := twice.Lsh(twice, 1).Cmp(new(big.Int).Abs(den))
+
+	away := false
+	switch mode {
+	case RoundHalfUp:
+		away = half >= 0
+	case RoundHalfEven:
+		away = half > 0 || half == 0 && q.Bit(0) == 1
+	case RoundHalfDown:
+		away = half > 0
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+// execDecimalBinOp executes the arithmetic and the comparisons of numbers when at least one of the operands is a
+// decimal. The other operand is promoted to a decimal, a float by the shortest decimal which reads back as the
+// same float, so `decimal("0.1") + 0.2` is exactly 0.3.
+func execDecimalBinOp(l, r value, op Symbol, mode RoundingMode) (interface{}, TypeFlags, error) {
+	d1, err := toDecimal(l.val)
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	if op == POWER {
+		ret, err := d1.Pow(r.val.(int64), mode)
+		if err != nil {
+			return nil, TypeNull, err
+		}
+		return ret, TypeDecimal, nil
+	}
+	d2, err := toDecimal(r.val)
+	if err != nil {
+		return nil, TypeNull, err
+	}
+
+	var ret Decimal
+	switch op {
+	case PLUS:
+		ret = d1.Add(d2)
+	case MINUS:
+		ret = d1.Sub(d2)
+	case MULTIPLY:
+		ret = d1.Mul(d2)
+	case DIVIDE:
+		ret, err = d1.Quo(d2, mode)
+	case MODULUS:
+		ret, err = d1.Rem(d2)
+	case GTE:
+		return d1.Cmp(d2) >= 0, TypeBool, nil
+	case GT:
+		return d1.Cmp(d2) > 0, TypeBool, nil
+	case LTE:
+		return d1.Cmp(d2) <= 0, TypeBool, nil
+	case LT:
+		return d1.Cmp(d2) < 0, TypeBool, nil
+	case EQ:
+		return d1.Cmp(d2) == 0, TypeBool, nil
+	case NEQ:
+		return d1.Cmp(d2) != 0, TypeBool, nil
+	default:
+		return nil, TypeNull, errors.New("engine: unreachable code")
+	}
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	if ret.scale > maxDecimalScale {
+		return nil, TypeNull, fmt.Errorf("engine: decimal result of operator [%v] is out of range", op)
+	}
+	return ret, TypeDecimal, nil
+}
@@ -0,0 +1,202 @@
+package executor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func TestDecimalOperator(t *testing.T) {
+	price, _ := executor.ParseDecimal("19.99")
+	params := executor.MapParameters{
+		"price":    price,
+		"quantity": 3,
+		"rate":     0.1,
+		"minInt":   int64(math.MinInt64),
+	}
+
+	cases := []struct {
+		exp     string
+		want    string
+		tp      executor.TypeFlags
+		wantErr bool
+	}{
+		{exp: `decimal("0.1") + decimal("0.2") == decimal("0.3")`, want: "true", tp: executor.TypeBool},
+		{exp: `decimal("0.1") + 0.2`, want: "0.3", tp: executor.TypeDecimal},
+		{exp: `decimal(0.1)`, want: "0.1", tp: executor.TypeDecimal},
+		{exp: `decimal("1.5e3")`, want: "1500", tp: executor.TypeDecimal},
+		{exp: `decimal("-0.050")`, want: "-0.050", tp: executor.TypeDecimal},
+		{exp: `price * quantity`, want: "59.97", tp: executor.TypeDecimal},
+		{exp: `price * quantity * (1 - rate)`, want: "53.973", tp: executor.TypeDecimal},
+		{exp: `price - 20`, want: "-0.01", tp: executor.TypeDecimal},
+		{exp: `-price`, want: "-19.99", tp: executor.TypeDecimal},
+		{exp: `price > 19.98 && price <= 19.99 && price != decimal(20)`, want: "true", tp: executor.TypeBool},
+		{exp: `price / 4`, want: "4.9975", tp: executor.TypeDecimal},
+		{exp: `decimal(10) / 3`, want: "3.3333333333333333", tp: executor.TypeDecimal},
+		{exp: `decimal(20) / 3`, want: "6.6666666666666667", tp: executor.TypeDecimal},
+		{exp: `decimal("10.00") / 4`, want: "2.5", tp: executor.TypeDecimal},
+		{exp: `price % 5`, want: "4.99", tp: executor.TypeDecimal},
+		{exp: `decimal("1.1") ** 2`, want: "1.21", tp: executor.TypeDecimal},
+		{exp: `decimal(2) ** -2`, want: "0.25", tp: executor.TypeDecimal},
+		{exp: `decimal(1) ** 9223372036854775807`, want: "1", tp: executor.TypeDecimal},
+		{exp: `round(decimal("2.345"), 2)`, want: "2.35", tp: executor.TypeDecimal},
+		{exp: `round(decimal("-2.5"))`, want: "-3", tp: executor.TypeDecimal},
+		{exp: `round(price, -1)`, want: "20", tp: executor.TypeDecimal},
+		{exp: `floor(decimal("-1.5")) + ceil(decimal("1.2"))`, want: "0", tp: executor.TypeDecimal},
+		{exp: `abs(decimal("-1.50"))`, want: "1.50", tp: executor.TypeDecimal},
+		{exp: `max(1, price, 2.5)`, want: "19.99", tp: executor.TypeDecimal},
+		{exp: `min(1, price, 2.5)`, want: "1", tp: executor.TypeDecimal},
+		{exp: `int(-price)`, want: "-19", tp: executor.TypeInteger},
+		{exp: `float(price)`, want: "19.99", tp: executor.TypeFloat},
+		{exp: `string(price * 2)`, want: "39.98", tp: executor.TypeString},
+		{exp: `price in [decimal("19.990"), 1]`, want: "true", tp: executor.TypeBool},
+		{exp: `price / 0`, wantErr: true},
+		{exp: `price % 0`, wantErr: true},
+		{exp: `price ** 0.5`, wantErr: true},
+		{exp: `decimal(1) ** minInt`, wantErr: true},
+		{exp: `decimal("0.1") ** 4611686018427387904`, wantErr: true},
+		{exp: `2 ** price`, wantErr: true},
+		{exp: `price & 1`, wantErr: true},
+		{exp: `price + "1"`, wantErr: true},
+		{exp: `decimal("1.2.3")`, wantErr: true},
+		{exp: `decimal("1e99999999")`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		program, err := build(t, c.exp)
+		var got interface{}
+		var tp executor.TypeFlags
+		if err == nil {
+			got, tp, err = program.Eval(params)
+		}
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if fmt.Sprint(got) != c.want || tp != c.tp {
+			t.Errorf("%q: got %v (%v), want %v (%v)", c.exp, got, tp, c.want, c.tp)
+		}
+	}
+}
+
+func TestProgram_WithRounding(t *testing.T) {
+	// the 16 digits kept by the division of 2 by 3
+	const third = "0.666666666666666"
+	cases := []struct {
+		mode executor.RoundingMode
+		want []string
+	}{
+		{mode: executor.RoundHalfUp, want: []string{"3", "-3", third + "7", "-" + third + "7"}},
+		{mode: executor.RoundHalfEven, want: []string{"2", "-2", third + "7", "-" + third + "7"}},
+		{mode: executor.RoundHalfDown, want: []string{"2", "-2", third + "7", "-" + third + "7"}},
+		{mode: executor.RoundUp, want: []string{"3", "-3", third + "7", "-" + third + "7"}},
+		{mode: executor.RoundDown, want: []string{"2", "-2", third + "6", "-" + third + "6"}},
+		{mode: executor.RoundCeiling, want: []string{"3", "-2", third + "7", "-" + third + "6"}},
+		{mode: executor.RoundFloor, want: []string{"2", "-3", third + "6", "-" + third + "7"}},
+	}
+	// the literals are not folded at compile time, they are rounded with the mode of the program
+	exps := []string{`round(decimal("2.5"))`, `round(decimal("-2.5"))`, `decimal(2) / 3`, `decimal(-2) / 3`}
+	programs := make([]*executor.Program, len(exps))
+	for i, exp := range exps {
+		programs[i] = compile(t, exp)
+	}
+
+	for _, c := range cases {
+		for i, program := range programs {
+			got, _, err := program.WithRounding(c.mode).Eval(nil)
+			if err != nil {
+				t.Errorf("%v %q: unexpected error: %v", c.mode, exps[i], err)
+				continue
+			}
+			if fmt.Sprint(got) != c.want[i] {
+				t.Errorf("%v %q: got %v, want %v", c.mode, exps[i], got, c.want[i])
+			}
+		}
+	}
+
+	if mode, err := executor.ParseRoundingMode("half_even"); err != nil || mode != executor.RoundHalfEven {
+		t.Errorf("ParseRoundingMode: got %v, %v", mode, err)
+	}
+	if _, err := executor.ParseRoundingMode("bankers"); err == nil {
+		t.Errorf("ParseRoundingMode: expected an error for an unknown mode")
+	}
+}
+
+func TestDecimal_MarshalJSON(t *testing.T) {
+	d, err := executor.ParseDecimal("12345678901234567890.000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]interface{}{"price": d})
+	if err != nil || string(b) != `{"price":12345678901234567890.000000000000000001}` {
+		t.Errorf("got %s, %v", b, err)
+	}
+}
+
+func TestBigInteger(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	params := executor.MapParameters{
+		"uid":    uint64(math.MaxUint64),
+		"small":  uint64(42),
+		"ids":    []uint64{1, math.MaxInt64 + 1, math.MaxUint64},
+		"huge":   huge,
+		"json":   json.Number("18446744073709551615"),
+		"jsonId": json.Number("1234567890123456789"),
+	}
+
+	cases := []struct {
+		exp     string
+		want    string
+		tp      executor.TypeFlags
+		wantErr bool
+	}{
+		{exp: `uid`, want: "18446744073709551615", tp: executor.TypeDecimal},
+		{exp: `uid == 18446744073709551615`, want: "true", tp: executor.TypeBool},
+		{exp: `uid == 18446744073709551614`, want: "false", tp: executor.TypeBool},
+		{exp: `uid == json && uid > 0xffff_ffff_ffff_fffe`, want: "true", tp: executor.TypeBool},
+		{exp: `uid in ids && 9223372036854775808 in ids && 9223372036854775807 not in ids`, want: "true",
+			tp: executor.TypeBool},
+		{exp: `small`, want: "42", tp: executor.TypeInteger},
+		{exp: `jsonId`, want: "1234567890123456789", tp: executor.TypeInteger},
+		{exp: `jsonId == 1234567890123456789 && jsonId != 1234567890123456788`, want: "true", tp: executor.TypeBool},
+		{exp: `huge + 1`, want: "123456789012345678901234567891", tp: executor.TypeDecimal},
+		{exp: `uid % 1024`, want: "1023", tp: executor.TypeDecimal},
+		{exp: `ids[2] - ids[1]`, want: "9223372036854775807", tp: executor.TypeDecimal},
+		{exp: `-9223372036854775808 == -9223372036854775807 - 1`, want: "true", tp: executor.TypeBool},
+		{exp: `string(uid)`, want: "18446744073709551615", tp: executor.TypeString},
+		{exp: `uid & 1`, wantErr: true},
+		{exp: `int(uid)`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		program, err := build(t, c.exp)
+		var got interface{}
+		var tp executor.TypeFlags
+		if err == nil {
+			got, tp, err = program.Eval(params)
+		}
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if fmt.Sprint(got) != c.want || tp != c.tp {
+			t.Errorf("%q: got %v (%v), want %v (%v)", c.exp, got, tp, c.want, c.tp)
+		}
+	}
+}
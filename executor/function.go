@@ -0,0 +1,208 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+
+	"github.com/qimengxingyuan/young_engine/token"
+)
+
+const (
+	argErrFmt   = "type mismatch for function [%s]: arg %d='%s', expected '%s'"
+	arityErrFmt = "function [%s] expects %s, but got %d"
+)
+
+// Function is a function that can be called from an expression, such as `len(name)`.
+type Function struct {
+	name string
+
+	// the set of types accepted by each parameter
+	params []TypeFlags
+	// the number of trailing parameters that may be omitted
+	optional int
+	// the last parameter may be repeated any number of times
+	variadic bool
+
+	// the type of the result, a set of types if it depends on the arguments
+	ret TypeFlags
+	// a pure function always returns the same result for the same arguments, so calls with literal arguments
+	// can be folded at compile time
+	pure bool
+	// the result of a call with a decimal argument depends on the rounding mode of the program
+	rounds bool
+
+	impl func(f *frame, args []value) (interface{}, TypeFlags, error)
+}
+
+func (fn *Function) Name() string {
+	return fn.name
+}
+
+// Return returns the type of the result of the function.
+func (fn *Function) Return() TypeFlags {
+	return fn.ret
+}
+
+func (fn *Function) IsPure() bool {
+	return fn.pure
+}
+
+// CheckArity returns an error if the function can not be called with n arguments.
+func (fn *Function) CheckArity(n int) error {
+	min := len(fn.params) - fn.optional
+	if fn.variadic && n >= min {
+		return nil
+	}
+	if n >= min && n <= len(fn.params) {
+		return nil
+	}
+
+	expected := fmt.Sprintf("%d arguments", len(fn.params))
+	switch {
+	case fn.variadic:
+		expected = fmt.Sprintf("at least %d arguments", min)
+	case fn.optional > 0:
+		expected = fmt.Sprintf("%d to %d arguments", min, len(fn.params))
+	}
+	return fmt.Errorf(arityErrFmt, fn.name, expected, n)
+}
+
+// paramType returns the set of types accepted by the i-th argument.
+func (fn *Function) paramType(i int) TypeFlags {
+	if i >= len(fn.params) {
+		return fn.params[len(fn.params)-1]
+	}
+	return fn.params[i]
+}
+
+// checkArgs ensures that the types of the arguments are accepted by the parameters, like the typeChecker of the
+// operators does for their operands.
+func (fn *Function) checkArgs(args []value) error {
+	for i, arg := range args {
+		if expected := fn.paramType(i); !expected.Accepts(arg.tp) {
+			return fmt.Errorf(argErrFmt, fn.name, i+1, arg.tp.String(), expected.String())
+		}
+	}
+	return nil
+}
+
+func (fn *Function) call(f *frame, args []value) (value, error) {
+	if err := fn.checkArgs(args); err != nil {
+		return value{}, err
+	}
+
+	ret, tp, err := fn.impl(f, args)
+	if err != nil {
+		return value{}, err
+	}
+	return value{val: ret, tp: tp}, nil
+}
+
+// GoFunc is a go function that can be registered to be called from expressions. It receives the values of the
+// arguments, already checked against the types of the parameters of the function.
+type GoFunc func(args ...interface{}) (interface{}, error)
+
+// Registry holds the functions that can be called by the expressions compiled with it, on top of the built-in
+// functions. Each engine owns its registry, functions registered in one of them are unknown to the others.
+type Registry struct {
+	mu        sync.RWMutex
+	functions map[string]*Function
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		functions: make(map[string]*Function),
+	}
+}
+
+// Register adds a go function that accepts arguments of the given types and returns a value of type ret. The
+// function is never folded at compile time, and a panic in it is recovered and reported as an evaluation error.
+func (r *Registry) Register(name string, params []TypeFlags, ret TypeFlags, fn GoFunc) error {
+	if !isFunctionName(name) {
+		return fmt.Errorf("engine: invalid function name '%s'", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("engine: function [%s] is nil", name)
+	}
+	for i, param := range params {
+		if param&TypeAny == 0 || param&^TypeAny != 0 {
+			return fmt.Errorf("engine: function [%s] has an invalid type for parameter %d", name, i+1)
+		}
+	}
+	if ret&TypeAny == 0 || ret&^TypeAny != 0 {
+		return fmt.Errorf("engine: function [%s] has an invalid return type", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exist := builtins[name]; exist {
+		return fmt.Errorf("engine: function [%s] is a built-in function", name)
+	}
+	if _, exist := r.functions[name]; exist {
+		return fmt.Errorf("engine: function [%s] is already registered", name)
+	}
+
+	r.functions[name] = &Function{
+		name:   name,
+		params: append([]TypeFlags(nil), params...),
+		ret:    ret,
+		impl:   goFuncImpl(name, ret, fn),
+	}
+	return nil
+}
+
+// Lookup returns the function of the given name, registered or built-in.
+func (r *Registry) Lookup(name string) (*Function, bool) {
+	if fn, exist := builtins[name]; exist {
+		return fn, true
+	}
+	if r == nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, exist := r.functions[name]
+	return fn, exist
+}
+
+// goFuncImpl adapts a registered go function to the calling convention of the executor.
+func goFuncImpl(name string, ret TypeFlags, fn GoFunc) func(f *frame, args []value) (interface{}, TypeFlags, error) {
+	return func(f *frame, args []value) (result interface{}, tp TypeFlags, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, tp, err = nil, TypeNull, fmt.Errorf("engine: function [%s] panicked: %v", name, r)
+			}
+		}()
+
+		goArgs := make([]interface{}, len(args))
+		for i, arg := range args {
+			goArgs[i] = arg.val
+		}
+
+		out, err := fn(goArgs...)
+		if err != nil {
+			return nil, TypeNull, fmt.Errorf("function [%s]: %w", name, err)
+		}
+
+		val, tp := getType(out)
+		if !ret.Accepts(tp) {
+			return nil, TypeNull, fmt.Errorf("engine: function [%s] returned '%s', expected '%s'",
+				name, tp.String(), ret.String())
+		}
+		return val, tp, nil
+	}
+}
+
+func isFunctionName(name string) bool {
+	if name == "" || token.Lookup(name) != token.Identifier {
+		return false
+	}
+	for i, ch := range name {
+		if !(unicode.IsLetter(ch) || ch == '_' || i > 0 && unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"fmt"
+	"time"
+)
+
+// MissingPolicy decides what a parameter that has not been provided by the caller evaluates to.
+type MissingPolicy int
+
+const (
+	// MissingStrict fails the evaluation with a MissingParameterError
+	MissingStrict MissingPolicy = iota
+	// MissingAsNull evaluates a missing parameter to null
+	MissingAsNull
+	// MissingAsDefault evaluates a missing parameter to the default value of the type expected by the operator or
+	// the function using it: false, 0, "", an empty list or map, and null when the type can not be determined.
+	// e.g. age is 0 in `age > 18` and name is "" in `name == "alice"`
+	MissingAsDefault
+)
+
+var missingPolicyNames = [...]string{
+	MissingStrict:    "strict",
+	MissingAsNull:    "null",
+	MissingAsDefault: "default",
+}
+
+func (m MissingPolicy) String() string {
+	if m >= 0 && int(m) < len(missingPolicyNames) {
+		return missingPolicyNames[m]
+	}
+	return fmt.Sprintf("MissingPolicy(%d)", int(m))
+}
+
+// ParseMissingPolicy returns the policy of the given name, "strict", "null" or "default". An empty name is
+// the strict policy.
+func ParseMissingPolicy(name string) (MissingPolicy, error) {
+	if name == "" {
+		return MissingStrict, nil
+	}
+	for policy, policyName := range missingPolicyNames {
+		if policyName == name {
+			return MissingPolicy(policy), nil
+		}
+	}
+	return MissingStrict, fmt.Errorf("engine: unknown missing parameter policy '%s'", name)
+}
+
+// typeDefault returns the default value of the expected type of a parameter.
+func typeDefault(expected TypeFlags) value {
+	switch {
+	case expected == TypeBool:
+		return value{val: false, tp: TypeBool}
+	case expected == TypeInteger, expected == TypeNumber:
+		return value{val: int64(0), tp: TypeInteger}
+	case expected == TypeFloat:
+		return value{val: 0.0, tp: TypeFloat}
+	case expected == TypeString:
+		return value{val: "", tp: TypeString}
+	case expected == TypeList:
+		return value{val: []interface{}{}, tp: TypeList}
+	case expected == TypeMap:
+		return value{val: map[string]interface{}{}, tp: TypeMap}
+	case expected == TypeTime:
+		return value{val: time.Time{}, tp: TypeTime}
+	case expected == TypeDuration:
+		return value{val: time.Duration(0), tp: TypeDuration}
+	case expected == TypeDecimal:
+		return value{val: Decimal{}, tp: TypeDecimal}
+	default:
+		return value{val: nil, tp: TypeNull}
+	}
+}
+
+// inferExpected records on every VALUE node of the tree the type expected by the operator or the function using
+// it, TypeNull when it can not be determined.
+func inferExpected(n *Node, expected TypeFlags) {
+	if n == nil {
+		return
+	}
+
+	switch n.symbol {
+	case VALUE:
+		n.expected = expected
+	case COALESCE:
+		inferExpected(n.leftNode, expected)
+		inferExpected(n.rightNode, expected)
+	case POSITIVE, NEGATIVE, MINUS, MULTIPLY, DIVIDE, MODULUS, POWER:
+		inferExpected(n.leftNode, TypeNumber)
+		inferExpected(n.rightNode, TypeNumber)
+	case BITAND, BITOR, BITXOR, BITNOT, SHIFTLEFT, SHIFTRIGHT:
+		inferExpected(n.leftNode, TypeInteger)
+		inferExpected(n.rightNode, TypeInteger)
+	case INVERT, AND, OR:
+		inferExpected(n.leftNode, TypeBool)
+		inferExpected(n.rightNode, TypeBool)
+	case MATCH, NOTMATCH:
+		inferExpected(n.leftNode, TypeString)
+		inferExpected(n.rightNode, TypeString)
+	case PLUS, GT, GTE, LT, LTE:
+		// a string or a number, like the other operand
+		inferExpected(n.leftNode, comparedType(n.rightNode, TypeNumber))
+		inferExpected(n.rightNode, comparedType(n.leftNode, TypeNumber))
+	case EQ, NEQ:
+		inferExpected(n.leftNode, comparedType(n.rightNode, TypeNull))
+		inferExpected(n.rightNode, comparedType(n.leftNode, TypeNull))
+	case IN, NOTIN:
+		inferExpected(n.leftNode, TypeNull)
+		inferExpected(n.rightNode, TypeList)
+	case MEMBER, INDEX:
+		inferExpected(n.leftNode, TypeNull)
+		inferExpected(n.rightNode, TypeNull)
+	case CONDITIONAL:
+		inferExpected(n.children[0], TypeBool)
+		inferExpected(n.children[1], expected)
+		inferExpected(n.children[2], expected)
+	case CALL:
+		for i, arg := range n.children {
+			param := n.function.paramType(i)
+			if !param.IsSingle() && param != TypeNumber {
+				param = TypeNull
+			}
+			inferExpected(arg, param)
+		}
+	case LIST:
+		for _, item := range n.children {
+			inferExpected(item, TypeNull)
+		}
+	}
+}
+
+// comparedType returns the type of the operand a value is compared to, or fallback if it is unknown.
+func comparedType(other *Node, fallback TypeFlags) TypeFlags {
+	tp, known := other.StaticType()
+	switch {
+	case !known:
+		return fallback
+	case tp.IsNumber():
+		return TypeNumber
+	case tp.IsString(), tp.IsBool():
+		return tp
+	default:
+		return fallback
+	}
+}
+
+// paramNames returns the names of the parameters read by the tree, in the order they first appear.
+func paramNames(n *Node, seen map[string]bool, names []string) []string {
+	if n == nil {
+		return names
+	}
+	if n.symbol == VALUE {
+		name := n.value.(string)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		return names
+	}
+
+	names = paramNames(n.leftNode, seen, names)
+	names = paramNames(n.rightNode, seen, names)
+	for _, child := range n.children {
+		names = paramNames(child, seen, names)
+	}
+	return names
+}
@@ -0,0 +1,65 @@
+package executor
+
+// The null policy. A value is null when it is the `null` literal, a parameter or member whose go value is nil
+// (such as a JSON null), a missing parameter or member in an optional position, or the result of an operation
+// propagating null:
+//   - arithmetic and bitwise operators, unary `+` `-` `~`, member access and index propagate null, `null + 1`
+//     is null and `user.profile.age` is null when profile is null
+//   - null is only equal to null, `null == 0` is false and `null != 0` is true
+//   - ordering comparisons and `=~` involving null are false, `!~` is true
+//   - `null in list` looks for a null item of the list, a null list is empty: `x in null` is false and
+//     `x not in null` is true
+//   - null is not a boolean, `!`, `&&`, `||` and the condition of `?:` report a type error, and the arguments of
+//     functions can not be null: use `??` to give them a default value, e.g. `flag ?? false`
+//
+// A missing parameter or member is an error, except on the left of `??` and when it is compared to null, where
+// it is null: `score ?? 0` and `user.email == null` hold when score and email have not been provided.
+
+// applyNull applies the null policy to an operation with a null operand. It reports false when the operator
+// handles null itself, or when none of the operands is null.
+func (n *Node) applyNull(left, right value) (value, bool) {
+	leftNull := n.leftNode != nil && left.tp.IsNull()
+	rightNull := n.rightNode != nil && right.tp.IsNull()
+	if !leftNull && !rightNull {
+		return value{}, false
+	}
+
+	switch n.symbol {
+	case PLUS, MINUS, MULTIPLY, DIVIDE, MODULUS, POWER, POSITIVE, NEGATIVE, MEMBER, INDEX,
+		BITAND, BITOR, BITXOR, BITNOT, SHIFTLEFT, SHIFTRIGHT:
+		return value{val: nil, tp: TypeNull}, true
+	case EQ:
+		return value{val: leftNull && rightNull, tp: TypeBool}, true
+	case NEQ:
+		return value{val: !(leftNull && rightNull), tp: TypeBool}, true
+	case GT, GTE, LT, LTE, MATCH:
+		return value{val: false, tp: TypeBool}, true
+	case NOTMATCH:
+		return value{val: true, tp: TypeBool}, true
+	case IN, NOTIN:
+		if rightNull {
+			return value{val: n.symbol == NOTIN, tp: TypeBool}, true
+		}
+	}
+	return value{}, false
+}
+
+// markOptional makes a missing parameter or member read by the node null instead of an error.
+func (n *Node) markOptional() {
+	for n != nil {
+		switch n.symbol {
+		case VALUE:
+			n.optional = true
+			return
+		case MEMBER, INDEX:
+			n.optional = true
+			n = n.leftNode
+		default:
+			return
+		}
+	}
+}
+
+func (n *Node) isNullLiteral() bool {
+	return n != nil && n.symbol == LITERAL && n.tp.IsNull()
+}
@@ -0,0 +1,83 @@
+package executor_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNullPolicy(t *testing.T) {
+	params := map[string]interface{}{
+		"n":    nil,
+		"x":    3,
+		"user": map[string]interface{}{"name": "alice", "profile": nil},
+		"tags": []interface{}{"a", nil},
+	}
+
+	cases := []struct {
+		exp  string
+		want interface{}
+		err  string
+	}{
+		// coalescing, a missing parameter or member is null on the left of ??
+		{exp: `score ?? 0`, want: int64(0)},
+		{exp: `x ?? 0`, want: int64(3)},
+		{exp: `n ?? 7`, want: int64(7)},
+		{exp: `score ?? 0 > 5`, want: false},
+		{exp: `a ?? b ?? "c"`, want: "c"},
+		{exp: `user.email ?? "none"`, want: "none"},
+		{exp: `user.profile.age ?? 18`, want: int64(18)},
+		{exp: `tags[5] ?? "z"`, want: "z"},
+		{exp: `null ?? x`, want: int64(3)},
+		{exp: `x ?? missing`, want: int64(3)},
+		// comparisons with null
+		{exp: `score == null`, want: true},
+		{exp: `null != score`, want: false},
+		{exp: `user.email == null`, want: true},
+		{exp: `n == null`, want: true},
+		{exp: `x == null`, want: false},
+		{exp: `n == 0`, want: false},
+		{exp: `n != ""`, want: true},
+		{exp: `n > 1 || n <= 1`, want: false},
+		{exp: `n =~ "a"`, want: false},
+		{exp: `n !~ "a"`, want: true},
+		// propagation
+		{exp: `n + 1`, want: nil},
+		{exp: `-n * 2`, want: nil},
+		{exp: `user.profile.age`, want: nil},
+		{exp: `(n + 1) ?? 0`, want: int64(0)},
+		{exp: `vip ? null : 1`, err: "No parameter 'vip' found"},
+		{exp: `x > 0 ? null : 1`, want: nil},
+		// membership
+		{exp: `null in tags`, want: true},
+		{exp: `null in ["a"]`, want: false},
+		{exp: `x in n`, want: false},
+		{exp: `x not in n`, want: true},
+		{exp: `[null, x]`, want: []interface{}{nil, int64(3)}},
+		// null is not a boolean, and is not accepted by functions
+		{exp: `!n`, err: "type mismatch for operator [!]: right='Null'"},
+		{exp: `n && true`, err: "type mismatch for operator [&&]: left='Null', right='boolean'"},
+		{exp: `n ? 1 : 2`, err: "type mismatch for operator [?:]: condition='Null', expected 'boolean'"},
+		{exp: `len(n)`, err: "type mismatch for function [len]: arg 1='Null', expected 'string|list|map'"},
+		// a missing parameter or member is still an error elsewhere
+		{exp: `score + 1 ?? 2`, err: "No parameter 'score' found"},
+		{exp: `user.email`, err: "No member 'email' found"},
+		{exp: `score > 1`, err: "No parameter 'score' found"},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.err != "" {
+			if err == nil || message(err) != c.err {
+				t.Errorf("%q: got error %v, want %q", c.exp, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
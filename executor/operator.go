@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 )
 
 // Parameters is a collection of named parameters that can be used by an EvaluableExpression to retrieve parameters
@@ -22,155 +23,384 @@ var DummyParameters = MapParameters(map[string]interface{}{})
 func (p MapParameters) Get(name string) (interface{}, error) {
 	value, found := p[name]
 	if !found {
-		errorMessage := "No parameter '" + name + "' found."
-		return nil, errors.New(errorMessage)
+		return nil, &MissingParameterError{Name: name}
 	}
 
 	return value, nil
 }
 
+// MissingParameterError is the error returned by Parameters.Get when the parameter has not been provided. A
+// missing parameter is null on the left of `??` and when it is compared to null, implementations of Parameters
+// should return this error, possibly wrapped, for it to be recognized.
+type MissingParameterError struct {
+	Name string
+}
+
+func (e *MissingParameterError) Error() string {
+	return "No parameter '" + e.Name + "' found"
+}
+
 var (
-	divideZeroErr = errors.New("engine: number divide by zero")
+	divideZeroErr    = errors.New("engine: number divide by zero")
+	negativeShiftErr = errors.New("engine: negative shift count")
 )
 
-type operator func(root, left *Node, right *Node, parameters Parameters) (interface{}, TypeFlags, error)
+const overflowErrFmt = "engine: integer overflow: %d %v %d"
+
+type operator func(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error)
 
-func noopOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
-	return right.value, right.tp, nil
+func noopOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return right.val, right.tp, nil
 }
 
 // +
-func addOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func addOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsString() && right.tp.IsString() {
-		return left.value.(string) + right.value.(string), TypeString, nil
+		return left.val.(string) + right.val.(string), TypeString, nil
+	} else if left.tp.IsTemporal() || right.tp.IsTemporal() {
+		return execTimeBinOp(left, right, PLUS)
+	} else if left.tp.IsDecimal() || right.tp.IsDecimal() {
+		return execDecimalBinOp(left, right, PLUS, f.rounding)
 	} else {
 		return execNumberBinOp(left, right, PLUS)
 	}
 }
 
 // -
-func subtractOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func subtractOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	if left.tp.IsTemporal() || right.tp.IsTemporal() {
+		return execTimeBinOp(left, right, MINUS)
+	}
+	if left.tp.IsDecimal() || right.tp.IsDecimal() {
+		return execDecimalBinOp(left, right, MINUS, f.rounding)
+	}
 	return execNumberBinOp(left, right, MINUS)
 }
 
 // -
-func negateOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func negateOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if right.tp == TypeFloat {
-		return -right.value.(float64), right.tp, nil
+		return -right.val.(float64), right.tp, nil
+	} else if right.tp == TypeDuration {
+		return -right.val.(time.Duration), right.tp, nil
+	} else if right.tp == TypeDecimal {
+		return right.val.(Decimal).Neg(), right.tp, nil
 	} else {
-		return -right.value.(int64), right.tp, nil
+		v := right.val.(int64)
+		if v == math.MinInt64 {
+			return nil, TypeNull, fmt.Errorf("engine: integer overflow: -(%d)", v)
+		}
+		return -v, right.tp, nil
 	}
 }
 
 // +
-func positOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
-	return right.value.(float64), right.tp, nil
+func positOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return right.val, right.tp, nil
 }
 
 // *
-func multiplyOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func multiplyOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	if left.tp.IsTemporal() || right.tp.IsTemporal() {
+		return execTimeBinOp(left, right, MULTIPLY)
+	}
+	if left.tp.IsDecimal() || right.tp.IsDecimal() {
+		return execDecimalBinOp(left, right, MULTIPLY, f.rounding)
+	}
 	return execNumberBinOp(left, right, MULTIPLY)
 }
 
 // /
-func divideOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func divideOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	if left.tp.IsTemporal() || right.tp.IsTemporal() {
+		return execTimeBinOp(left, right, DIVIDE)
+	}
+	if left.tp.IsDecimal() || right.tp.IsDecimal() {
+		return execDecimalBinOp(left, right, DIVIDE, f.rounding)
+	}
 	return execNumberBinOp(left, right, DIVIDE)
 }
 
 // %
-func modulusOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func modulusOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	if left.tp.IsDecimal() || right.tp.IsDecimal() {
+		return execDecimalBinOp(left, right, MODULUS, f.rounding)
+	}
 	return execNumberBinOp(left, right, MODULUS)
 }
 
+// **
+func powerOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	if left.tp.IsDecimal() || right.tp.IsDecimal() {
+		return execDecimalBinOp(left, right, POWER, f.rounding)
+	}
+	return execNumberBinOp(left, right, POWER)
+}
+
+// &
+func bitAndOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return execIntegerBinOp(left, right, BITAND)
+}
+
+// |
+func bitOrOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return execIntegerBinOp(left, right, BITOR)
+}
+
+// ^
+func bitXorOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return execIntegerBinOp(left, right, BITXOR)
+}
+
+// <<
+func shiftLeftOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return execIntegerBinOp(left, right, SHIFTLEFT)
+}
+
+// >>
+func shiftRightOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return execIntegerBinOp(left, right, SHIFTRIGHT)
+}
+
+// ~
+func bitNotOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return ^right.val.(int64), TypeInteger, nil
+}
+
 // >=
-func gteOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func gteOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsNumber() && right.tp.IsNumber() {
 		return execNumberBinOp(left, right, GTE)
+	} else if left.tp.IsTemporal() {
+		return execTimeBinOp(left, right, GTE)
 	} else {
-		return left.value.(string) >= right.value.(string), TypeBool, nil
+		return left.val.(string) >= right.val.(string), TypeBool, nil
 	}
 }
 
 // >
-func gtOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func gtOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsNumber() && right.tp.IsNumber() {
 		return execNumberBinOp(left, right, GT)
+	} else if left.tp.IsTemporal() {
+		return execTimeBinOp(left, right, GT)
 	} else {
-		return left.value.(string) > right.value.(string), TypeBool, nil
+		return left.val.(string) > right.val.(string), TypeBool, nil
 	}
 }
 
 // <=
-func lteOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func lteOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsNumber() && right.tp.IsNumber() {
 		return execNumberBinOp(left, right, LTE)
+	} else if left.tp.IsTemporal() {
+		return execTimeBinOp(left, right, LTE)
 	} else {
-		return left.value.(string) <= right.value.(string), TypeBool, nil
+		return left.val.(string) <= right.val.(string), TypeBool, nil
 	}
 }
 
 // <
-func ltOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func ltOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsNumber() && right.tp.IsNumber() {
 		return execNumberBinOp(left, right, LT)
+	} else if left.tp.IsTemporal() {
+		return execTimeBinOp(left, right, LT)
 	} else {
-		return left.value.(string) < right.value.(string), TypeBool, nil
+		return left.val.(string) < right.val.(string), TypeBool, nil
 	}
 }
 
 // ==
-func equalOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func equalOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsNumber() && right.tp.IsNumber() {
 		return execNumberBinOp(left, right, EQ)
+	} else if left.tp.IsTemporal() {
+		return execTimeBinOp(left, right, EQ)
 	} else if left.tp.IsString() && right.tp.IsString() {
-		return left.value.(string) == right.value.(string), TypeBool, nil
+		return left.val.(string) == right.val.(string), TypeBool, nil
+	} else if left.tp.IsList() || left.tp.IsMap() {
+		return equals(left, right), TypeBool, nil
 	} else {
-		return left.value.(bool) == right.value.(bool), TypeBool, nil
+		return left.val.(bool) == right.val.(bool), TypeBool, nil
 	}
 }
 
 // !=
-func notEqualOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func notEqualOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	if left.tp.IsNumber() && right.tp.IsNumber() {
 		return execNumberBinOp(left, right, NEQ)
+	} else if left.tp.IsTemporal() {
+		return execTimeBinOp(left, right, NEQ)
 	} else if left.tp.IsString() && right.tp.IsString() {
-		return left.value.(string) != right.value.(string), TypeBool, nil
+		return left.val.(string) != right.val.(string), TypeBool, nil
+	} else if left.tp.IsList() || left.tp.IsMap() {
+		return !equals(left, right), TypeBool, nil
 	} else {
-		return left.value.(bool) != right.value.(bool), TypeBool, nil
+		return left.val.(bool) != right.val.(bool), TypeBool, nil
 	}
 }
 
 // &&
-func andOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
-	return left.value.(bool) && right.value.(bool), TypeBool, nil
+func andOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return left.val.(bool) && right.val.(bool), TypeBool, nil
 }
 
 // ||
-func orOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
-	return left.value.(bool) || right.value.(bool), TypeBool, nil
+func orOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return left.val.(bool) || right.val.(bool), TypeBool, nil
 }
 
 // !
-func invertOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
-	return !right.value.(bool), TypeBool, nil
+func invertOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return !right.val.(bool), TypeBool, nil
+}
+
+// in
+func inOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return contains(right.val.([]interface{}), left), TypeBool, nil
+}
+
+// not in
+func notInOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	return !contains(right.val.([]interface{}), left), TypeBool, nil
+}
+
+// =~
+func matchOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	re, err := root.regexp(right)
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	return re.MatchString(left.val.(string)), TypeBool, nil
+}
+
+// !~
+func notMatchOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	re, err := root.regexp(right)
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	return !re.MatchString(left.val.(string)), TypeBool, nil
+}
+
+// contains reports whether the list holds an element equal to v. An element of a type that can not be compared
+// with v is never equal to it.
+func contains(list []interface{}, v value) bool {
+	for _, elem := range list {
+		if equals(v, elementValue(elem)) {
+			return true
+		}
+	}
+	return false
+}
+
+func equals(l, r value) bool {
+	switch {
+	case l.tp.IsNumber() && r.tp.IsNumber():
+		eq, _, _ := execNumberBinOp(l, r, EQ)
+		return eq.(bool)
+	case l.tp == r.tp && (l.tp.IsString() || l.tp.IsBool() || l.tp.IsDuration()):
+		return l.val == r.val
+	case l.tp.IsTime() && r.tp.IsTime():
+		return l.val.(time.Time).Equal(r.val.(time.Time))
+	case l.tp.IsNull() && r.tp.IsNull():
+		return true
+	case l.tp.IsList() && r.tp.IsList():
+		// lists are equal when their elements are equal one by one
+		left, right := l.val.([]interface{}), r.val.([]interface{})
+		if len(left) != len(right) {
+			return false
+		}
+		for i := range left {
+			if !equals(elementValue(left[i]), elementValue(right[i])) {
+				return false
+			}
+		}
+		return true
+	case l.tp.IsMap() && r.tp.IsMap():
+		// maps are equal when they have the same keys and their values are equal key by key
+		left, right := l.val.(map[string]interface{}), r.val.(map[string]interface{})
+		if len(left) != len(right) {
+			return false
+		}
+		for key, elem := range left {
+			other, found := right[key]
+			if !found || !equals(elementValue(elem), elementValue(other)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// elementValue returns the value of an element of a list or a map.
+func elementValue(elem interface{}) value {
+	val, tp := getType(elem)
+	return value{val: val, tp: tp}
+}
+
+// . []
+func indexOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	var elem interface{}
+	switch container := left.val.(type) {
+	case map[string]interface{}:
+		member, found := container[right.val.(string)]
+		if !found {
+			if root.optional {
+				return nil, TypeNull, nil
+			}
+			errorMessage := "No member '" + right.val.(string) + "' found"
+			return nil, TypeNull, errors.New(errorMessage)
+		}
+		elem = member
+	case []interface{}:
+		index := right.val.(int64)
+		if index < 0 || index >= int64(len(container)) {
+			if root.optional {
+				return nil, TypeNull, nil
+			}
+			return nil, TypeNull, fmt.Errorf("engine: index %d out of range [0:%d]", index, len(container))
+		}
+		elem = container[index]
+	}
+
+	val, tp := getType(elem)
+	if tp.IsNull() && elem != nil {
+		return val, tp, errors.New("unsupported type")
+	}
+	return val, tp, nil
 }
 
 // value
-func parameterOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
-	value, err := parameters.Get(root.value.(string))
+func parameterOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
+	param, err := f.parameters.Get(root.value.(string))
 	if err != nil {
+		var missing *MissingParameterError
+		if !errors.As(err, &missing) {
+			return nil, TypeNull, err
+		}
+		switch {
+		case root.optional || f.missing == MissingAsNull:
+			return nil, TypeNull, nil
+		case f.missing == MissingAsDefault:
+			def := typeDefault(root.expected)
+			return def.val, def.tp, nil
+		}
 		return nil, TypeNull, err
 	}
 
-	val, tp := getType(value)
-	if tp.IsNull() {
+	val, tp := getType(param)
+	if tp.IsNull() && param != nil {
 		return val, tp, errors.New("unsupported type")
 	}
-	return value, tp, nil
+	return val, tp, nil
 }
 
 // literal
-func literalOperator(root, left, right *Node, parameters Parameters) (interface{}, TypeFlags, error) {
+func literalOperator(root *Node, left, right value, f *frame) (interface{}, TypeFlags, error) {
 	return root.value, root.tp, nil
 }
 
@@ -180,35 +410,57 @@ func int2float(n interface{}) float64 {
 		return float64(v)
 	case float64:
 		return v
+	case Decimal:
+		return v.Float64()
 	default:
 		msg := fmt.Sprintf("unreachable code:%v", v)
 		panic(msg)
 	}
 }
 
-func execNumberBinOp(l, r *Node, op Symbol) (interface{}, TypeFlags, error) {
-	v1, t1 := l.value.(int64)
-	v2, t2 := r.value.(int64)
+// execNumberBinOp executes the arithmetic and the comparisons of numbers:
+//   - an integer and an integer give an integer, an overflow of int64 is an error rather than wrapping around
+//   - an integer divided by an integer is an integer when the division is exact, otherwise a float
+//   - an integer and a float give a float, the decimals are handled by execDecimalBinOp
+//   - integers and floats are compared exactly, `1 == 1.0` is true and 2^53 + 1 is greater than float(2^53)
+//   - a division or a modulo by zero is an error, for floats too
+func execNumberBinOp(l, r value, op Symbol) (interface{}, TypeFlags, error) {
+	if l.tp.IsDecimal() || r.tp.IsDecimal() {
+		// the arithmetic operators pass the rounding mode of the program themselves, the comparisons never round
+		return execDecimalBinOp(l, r, op, RoundHalfUp)
+	}
+
+	v1, t1 := l.val.(int64)
+	v2, t2 := r.val.(int64)
 
 	var v3, v4 float64
 	isInt := t1 && t2
-	if !isInt || op == DIVIDE {
-		v3, v4 = int2float(l.value), int2float(r.value)
+	if !isInt || op == DIVIDE || op == POWER {
+		v3, v4 = int2float(l.val), int2float(r.val)
 	}
 	switch op {
 	case PLUS:
 		if isInt {
-			return v1 + v2, TypeInteger, nil
+			if ret, ok := addInt(v1, v2); ok {
+				return ret, TypeInteger, nil
+			}
+			return nil, TypeNull, fmt.Errorf(overflowErrFmt, v1, op, v2)
 		}
 		return v3 + v4, TypeFloat, nil
 	case MINUS:
 		if isInt {
-			return v1 - v2, TypeInteger, nil
+			if ret, ok := subInt(v1, v2); ok {
+				return ret, TypeInteger, nil
+			}
+			return nil, TypeNull, fmt.Errorf(overflowErrFmt, v1, op, v2)
 		}
 		return v3 - v4, TypeFloat, nil
 	case MULTIPLY:
 		if isInt {
-			return v1 * v2, TypeInteger, nil
+			if ret, ok := mulInt(v1, v2); ok {
+				return ret, TypeInteger, nil
+			}
+			return nil, TypeNull, fmt.Errorf(overflowErrFmt, v1, op, v2)
 		}
 		return v3 * v4, TypeFloat, nil
 	case DIVIDE:
@@ -216,6 +468,9 @@ func execNumberBinOp(l, r *Node, op Symbol) (interface{}, TypeFlags, error) {
 			if v2 == 0 {
 				return nil, TypeNull, divideZeroErr
 			}
+			if v1 == math.MinInt64 && v2 == -1 {
+				return nil, TypeNull, fmt.Errorf(overflowErrFmt, v1, op, v2)
+			}
 			if v1%v2 == 0 {
 				return v1 / v2, TypeInteger, nil
 			}
@@ -226,41 +481,181 @@ func execNumberBinOp(l, r *Node, op Symbol) (interface{}, TypeFlags, error) {
 		return v3 / v4, TypeFloat, nil
 	case MODULUS:
 		if isInt {
+			if v2 == 0 {
+				return nil, TypeNull, divideZeroErr
+			}
 			return v1 % v2, TypeInteger, nil
 		}
-		return math.Mod(v3, v4), TypeInteger, nil
+		if v4 == 0.0 {
+			return nil, TypeNull, divideZeroErr
+		}
+		return math.Mod(v3, v4), TypeFloat, nil
+	case POWER:
+		// a negative exponent of an integer gives a fraction, like in python `2 ** -1` is 0.5
+		if isInt && v2 >= 0 {
+			if ret, ok := intPow(v1, v2); ok {
+				return ret, TypeInteger, nil
+			}
+			return nil, TypeNull, fmt.Errorf(overflowErrFmt, v1, op, v2)
+		}
+		return math.Pow(v3, v4), TypeFloat, nil
+	case GTE, GT, LTE, LT, EQ, NEQ:
+		c, ordered := cmpNumbers(l.val, r.val)
+		return compared(op, c, ordered), TypeBool, nil
+	default:
+		return nil, TypeNull, errors.New("engine: unreachable code")
+	}
+}
 
+// compared returns the result of the comparison op of two numbers whose order is c, as returned by cmpNumbers.
+func compared(op Symbol, c int, ordered bool) bool {
+	switch op {
 	case GTE:
-		if isInt {
-			return v1 >= v2, TypeBool, nil
-		}
-		return v3 >= v4, TypeBool, nil
+		return ordered && c >= 0
 	case GT:
-		if isInt {
-			return v1 > v2, TypeBool, nil
-		}
-		return v3 > v4, TypeBool, nil
+		return ordered && c > 0
 	case LTE:
-		if isInt {
-			return v1 <= v2, TypeBool, nil
-		}
-		return v3 <= v4, TypeBool, nil
+		return ordered && c <= 0
 	case LT:
-		if isInt {
-			return v1 < v2, TypeBool, nil
-		}
-		return v3 < v4, TypeBool, nil
+		return ordered && c < 0
 	case EQ:
-		if isInt {
-			return v1 == v2, TypeBool, nil
+		return ordered && c == 0
+	default:
+		return !ordered || c != 0
+	}
+}
+
+// addInt, subInt and mulInt report false if the result overflows int64.
+func addInt(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+func subInt(a, b int64) (int64, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return c, false
+	}
+	return c, c/b == a
+}
+
+// cmpNumbers compares two integers or floats and returns -1, 0 or +1. An integer compared to a float is not
+// converted to a float, which could round it. It reports false if one of them is NaN, NaN is not ordered.
+func cmpNumbers(l, r interface{}) (int, bool) {
+	i1, isInt1 := l.(int64)
+	i2, isInt2 := r.(int64)
+	switch {
+	case isInt1 && isInt2:
+		return cmpInt(i1, i2), true
+	case isInt1:
+		c, ordered := cmpIntFloat(i1, r.(float64))
+		return c, ordered
+	case isInt2:
+		c, ordered := cmpIntFloat(i2, l.(float64))
+		return -c, ordered
+	}
+
+	f1, f2 := l.(float64), r.(float64)
+	switch {
+	case math.IsNaN(f1) || math.IsNaN(f2):
+		return 0, false
+	case f1 < f2:
+		return -1, true
+	case f1 > f2:
+		return 1, true
+	}
+	return 0, true
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cmpIntFloat compares the integer i with the float f exactly.
+func cmpIntFloat(i int64, f float64) (int, bool) {
+	switch {
+	case math.IsNaN(f):
+		return 0, false
+	case f >= math.MaxInt64: // 2^63, the float nearest to MaxInt64
+		return -1, true
+	case f < math.MinInt64:
+		return 1, true
+	}
+
+	// f is within the range of int64, compare the integral parts then the fraction
+	t := math.Trunc(f)
+	if c := cmpInt(i, int64(t)); c != 0 {
+		return c, true
+	}
+	switch {
+	case f > t:
+		return -1, true
+	case f < t:
+		return 1, true
+	}
+	return 0, true
+}
+
+// execIntegerBinOp executes the bitwise operators, both operands are integers. The right operand of a shift is the
+// number of bits, a negative one is an error. `<<` reports an overflow when it shifts out bits which are not copies
+// of the sign bit, and `>>` by 64 bits or more gives 0, or -1 for a negative number.
+func execIntegerBinOp(l, r value, op Symbol) (interface{}, TypeFlags, error) {
+	v1, v2 := l.val.(int64), r.val.(int64)
+	switch op {
+	case BITAND:
+		return v1 & v2, TypeInteger, nil
+	case BITOR:
+		return v1 | v2, TypeInteger, nil
+	case BITXOR:
+		return v1 ^ v2, TypeInteger, nil
+	case SHIFTLEFT, SHIFTRIGHT:
+		if v2 < 0 {
+			return nil, TypeNull, negativeShiftErr
 		}
-		return v3 == v4, TypeBool, nil
-	case NEQ:
-		if isInt {
-			return v1 != v2, TypeBool, nil
+		if op == SHIFTLEFT {
+			ret := v1 << uint64(v2)
+			if ret>>uint64(v2) != v1 {
+				return nil, TypeNull, fmt.Errorf(overflowErrFmt, v1, op, v2)
+			}
+			return ret, TypeInteger, nil
 		}
-		return v3 != v4, TypeBool, nil
+		return v1 >> uint64(v2), TypeInteger, nil
 	default:
 		return nil, TypeNull, errors.New("engine: unreachable code")
 	}
 }
+
+// intPow raises base to the non-negative exponent by squaring, it reports false if the result overflows int64.
+func intPow(base, exp int64) (int64, bool) {
+	ret, ok := int64(1), true
+	for exp > 0 {
+		if exp&1 == 1 {
+			ret, ok = mulInt(ret, base)
+			if !ok {
+				return ret, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			base, ok = mulInt(base, base)
+			if !ok {
+				return base, false
+			}
+		}
+	}
+	return ret, true
+}
@@ -0,0 +1,391 @@
+package executor_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func TestInOperator(t *testing.T) {
+	params := map[string]interface{}{
+		"city":   "sh",
+		"uid":    uint32(1002),
+		"ids":    []int{1001, 1002},
+		"scores": []interface{}{1.5, 2},
+		"names":  [2]string{"a", "b"},
+		"bad":    []interface{}{struct{}{}},
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		wantErr bool
+	}{
+		{exp: `city in ("bj", "sh", "gz")`, want: true},
+		{exp: `city not in ("bj", "sh", "gz")`, want: false},
+		{exp: `city in ["bj", "gz"]`, want: false},
+		{exp: `uid in [1001, 1002]`, want: true},
+		{exp: `uid in ids`, want: true},
+		{exp: `uid - 2 not in ids`, want: true},
+		{exp: `2 in scores`, want: true},
+		{exp: `2.0 in scores && 1.5 in scores`, want: true},
+		{exp: `"b" in names`, want: true},
+		{exp: `uid in ["1002", true]`, want: false},
+		{exp: `city in []`, want: false},
+		{exp: `[uid, city, [1]]`, want: []interface{}{int64(1002), "sh", []interface{}{int64(1)}}},
+		{exp: `city in ("sh")`, wantErr: true},
+		{exp: `ids in [ids]`, wantErr: true},
+		{exp: `1 in bad`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
+
+// TestEqualOperator_List compares the lists element by element, in the folding of constants and on parameters.
+func TestEqualOperator_List(t *testing.T) {
+	params := map[string]interface{}{
+		"l":      []interface{}{int64(1), "a"},
+		"ids":    []int{1, 2},
+		"nested": [][]int{{1}, {2, 3}},
+	}
+
+	cases := []struct {
+		exp  string
+		want bool
+	}{
+		{exp: `[1] == [1]`, want: true},
+		{exp: `[1] != [1]`, want: false},
+		{exp: `[1, "a"] == [1, "b"]`, want: false},
+		{exp: `[] == []`, want: true},
+		{exp: `l == l`, want: true},
+		{exp: `l != l`, want: false},
+		{exp: `l == [1, "a"]`, want: true},
+		{exp: `ids == [1.0, 2]`, want: true},
+		{exp: `ids == [1]`, want: false},
+		{exp: `ids != [2, 1]`, want: true},
+		{exp: `nested == [[1], [2, 3]]`, want: true},
+		{exp: `nested == [[1], [2, "3"]]`, want: false},
+	}
+
+	for _, c := range cases {
+		program := compile(t, c.exp)
+		for _, eval := range []func(executor.Parameters) (interface{}, executor.TypeFlags, error){
+			program.Eval, program.Root().Eval,
+		} {
+			got, _, err := eval(executor.MapParameters(params))
+			if err != nil || got != c.want {
+				t.Errorf("%q: got %v %v, want %v", c.exp, got, err, c.want)
+			}
+		}
+	}
+}
+
+// TestEqualOperator_Map compares the maps key by key.
+func TestEqualOperator_Map(t *testing.T) {
+	params := map[string]interface{}{
+		"m":     map[string]interface{}{"a": 1, "b": []int{2}},
+		"same":  map[string]int64{"a": 1},
+		"other": map[string]interface{}{"a": 1.0},
+		"empty": map[string]interface{}{},
+	}
+
+	cases := []struct {
+		exp  string
+		want bool
+	}{
+		{exp: `m == m`, want: true},
+		{exp: `m != m`, want: false},
+		{exp: `m.b == [2]`, want: true},
+		{exp: `same == other`, want: true},
+		{exp: `same != m`, want: true},
+		{exp: `empty == same`, want: false},
+	}
+
+	for _, c := range cases {
+		program := compile(t, c.exp)
+		for _, eval := range []func(executor.Parameters) (interface{}, executor.TypeFlags, error){
+			program.Eval, program.Root().Eval,
+		} {
+			got, _, err := eval(executor.MapParameters(params))
+			if err != nil || got != c.want {
+				t.Errorf("%q: got %v %v, want %v", c.exp, got, err, c.want)
+			}
+		}
+	}
+}
+
+func TestIndexOperator(t *testing.T) {
+	params := map[string]interface{}{
+		"user": map[string]interface{}{
+			"name":    "young",
+			"profile": map[string]interface{}{"age": 20, "tags": []string{"vip", "new"}},
+		},
+		"order": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"price": 12.5, "count": int32(2)},
+				map[string]interface{}{"price": 3.0, "count": int32(1)},
+			},
+		},
+		"attrs":  map[string]string{"x-key": "value"},
+		"matrix": [][]int{{1, 2}, {3, 4}},
+		"i":      1,
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		wantErr bool
+	}{
+		{exp: `user.profile.age`, want: int64(20)},
+		{exp: `user.profile.age >= 18 && user.name == "young"`, want: true},
+		{exp: `user["profile"]["age"] + 1`, want: int64(21)},
+		{exp: `"vip" in user.profile.tags`, want: true},
+		{exp: `user.profile.tags[i]`, want: "new"},
+		{exp: `order.items[0].price * order.items[0].count`, want: 25.0},
+		{exp: `order.items[i].price`, want: 3.0},
+		{exp: `attrs["x-key"]`, want: "value"},
+		{exp: `matrix[1][0] + matrix[0][i]`, want: int64(5)},
+		{exp: `-matrix[1][1]`, want: int64(-4)},
+		{exp: `[10, 20, 30][i + 1]`, want: int64(30)},
+		{exp: `(user.profile).age`, want: int64(20)},
+		{exp: `user.missing`, wantErr: true},
+		{exp: `order.items[2]`, wantErr: true},
+		{exp: `order.items[-1]`, wantErr: true},
+		{exp: `order.items["0"]`, wantErr: true},
+		{exp: `user[0]`, wantErr: true},
+		{exp: `user.name.first`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestMatchOperator(t *testing.T) {
+	params := map[string]interface{}{
+		"ua":      "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X)",
+		"email":   "alice@example.com",
+		"pattern": `@example\.(com|org)$`,
+		"bad":     "(",
+		"age":     18,
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		wantErr bool
+	}{
+		{exp: `ua =~ "^Mozilla/"`, want: true},
+		{exp: `ua =~ "Android"`, want: false},
+		{exp: `ua !~ "Android"`, want: true},
+		{exp: "email =~ `^[a-z]+@`", want: true},
+		{exp: `email =~ pattern`, want: true},
+		{exp: `email !~ pattern`, want: false},
+		{exp: `"bob@example.org" =~ pattern`, want: true},
+		{exp: `ua =~ "iPhone" && email =~ "[.]com$"`, want: true},
+		{exp: `email =~ "\\.com$" && email !~ "\\.org$"`, want: true},
+		{exp: `lower(ua) =~ ("^" + "mozilla")`, want: true},
+		{exp: `email =~ bad`, wantErr: true},
+		{exp: `age =~ "1"`, wantErr: true},
+		{exp: `ua =~ age`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestBitwiseOperator(t *testing.T) {
+	params := map[string]interface{}{
+		"perm":  6,
+		"flags": uint8(0x81),
+		"n":     -8,
+		"f":     2.0,
+		"s":     "1",
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		wantErr bool
+	}{
+		{exp: `perm & 4 != 0`, want: true},
+		{exp: `perm & 1 == 0`, want: true},
+		{exp: `perm | 1`, want: int64(7)},
+		{exp: `perm ^ 3`, want: int64(5)},
+		{exp: `1 | 2 ^ 3 & 4`, want: int64(3)},
+		{exp: `~perm`, want: int64(-7)},
+		{exp: `flags & ~1`, want: int64(0x80)},
+		{exp: `1 << 3 + 1`, want: int64(16)},
+		{exp: `perm << 2 >> 1`, want: int64(12)},
+		{exp: `perm - 2 - 1`, want: int64(3)},
+		{exp: `perm / 2 * 3`, want: int64(9)},
+		{exp: `n >> 1`, want: int64(-4)},
+		{exp: `n >> 64`, want: int64(-1)},
+		{exp: `0 << 64`, want: int64(0)},
+		{exp: `1 << 62`, want: int64(1 << 62)},
+		{exp: `-1 << 63`, want: int64(math.MinInt64)},
+		{exp: `2 ** 10`, want: int64(1024)},
+		{exp: `2 ** 3 ** 2`, want: int64(512)},
+		{exp: `-2 ** 2`, want: int64(-4)},
+		{exp: `(-2) ** 2`, want: int64(4)},
+		{exp: `2 ** -1`, want: 0.5},
+		{exp: `f ** 3`, want: 8.0},
+		{exp: `3 * 2 ** 2`, want: int64(12)},
+		{exp: `perm << -1`, wantErr: true},
+		{exp: `1 << 64`, wantErr: true},
+		{exp: `1 << 63`, wantErr: true},
+		{exp: `perm << 61`, wantErr: true},
+		{exp: `perm & f`, wantErr: true},
+		{exp: `~f`, wantErr: true},
+		{exp: `s | 1`, wantErr: true},
+		{exp: `s ** 2`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, _, err := run(t, c.exp, params)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestNumericTower(t *testing.T) {
+	params := map[string]interface{}{
+		"max":  int64(math.MaxInt64),
+		"min":  int64(math.MinInt64),
+		"big":  int64(1<<53 + 1),
+		"nan":  math.NaN(),
+		"zero": 0,
+		"half": 0.5,
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		tp      executor.TypeFlags
+		wantErr string
+	}{
+		// promotion
+		{exp: `1 + 2`, want: int64(3), tp: executor.TypeInteger},
+		{exp: `1 + 2.0`, want: 3.0, tp: executor.TypeFloat},
+		{exp: `6 / 3`, want: int64(2), tp: executor.TypeInteger},
+		{exp: `7 / 2`, want: 3.5, tp: executor.TypeFloat},
+		{exp: `7 % 3`, want: int64(1), tp: executor.TypeInteger},
+		{exp: `-7 % 3`, want: int64(-1), tp: executor.TypeInteger},
+		{exp: `7.5 % 2`, want: 1.5, tp: executor.TypeFloat},
+		{exp: `7 % 2.5`, want: 2.0, tp: executor.TypeFloat},
+		{exp: `2 ** 62`, want: int64(1 << 62), tp: executor.TypeInteger},
+		{exp: `(-2) ** 63`, want: int64(math.MinInt64), tp: executor.TypeInteger},
+
+		// cross-type equality and exact comparisons
+		{exp: `1 == 1.0`, want: true, tp: executor.TypeBool},
+		{exp: `1 != 1.0`, want: false, tp: executor.TypeBool},
+		{exp: `2.5 == 2`, want: false, tp: executor.TypeBool},
+		{exp: `1 in [1.0, 2.0]`, want: true, tp: executor.TypeBool},
+		{exp: `big == float(big)`, want: false, tp: executor.TypeBool},
+		{exp: `big > float(big)`, want: true, tp: executor.TypeBool},
+		{exp: `max < 9223372036854775807.0`, want: true, tp: executor.TypeBool},
+		{exp: `min == -9223372036854775808.0`, want: true, tp: executor.TypeBool},
+		{exp: `-9223372036854775808`, want: int64(math.MinInt64), tp: executor.TypeInteger},
+		{exp: `min == -9223372036854775808`, want: true, tp: executor.TypeBool},
+		{exp: `-1 < -0.5 && -0.5 < 0 && 0 < half`, want: true, tp: executor.TypeBool},
+		{exp: `nan == nan || nan == 1 || nan < 1 || nan >= 1`, want: false, tp: executor.TypeBool},
+		{exp: `nan != nan && nan != 1`, want: true, tp: executor.TypeBool},
+		{exp: `1 == "1"`, wantErr: "type mismatch"},
+		{exp: `1 == true`, wantErr: "type mismatch"},
+
+		// overflow
+		{exp: `(max - 1) + 1`, want: int64(math.MaxInt64), tp: executor.TypeInteger},
+		{exp: `min + max`, want: int64(-1), tp: executor.TypeInteger},
+		{exp: `max + 1`, wantErr: "integer overflow"},
+		{exp: `min - 1`, wantErr: "integer overflow"},
+		{exp: `max * 2`, wantErr: "integer overflow"},
+		{exp: `min * -1`, wantErr: "integer overflow"},
+		{exp: `min / -1`, wantErr: "integer overflow"},
+		{exp: `-min`, wantErr: "integer overflow"},
+		{exp: `abs(min)`, wantErr: "integer overflow"},
+		{exp: `2 ** 63`, wantErr: "integer overflow"},
+		{exp: `10 ** 19`, wantErr: "integer overflow"},
+		{exp: `max + 1.0`, want: float64(math.MaxInt64) + 1, tp: executor.TypeFloat},
+		{exp: `min % (-1)`, want: int64(0), tp: executor.TypeInteger},
+
+		// division and modulo by zero
+		{exp: `1 / zero`, wantErr: "divide by zero"},
+		{exp: `1.5 / zero`, wantErr: "divide by zero"},
+		{exp: `1 % zero`, wantErr: "divide by zero"},
+		{exp: `1.5 % zero`, wantErr: "divide by zero"},
+		{exp: `1 % 0.0`, wantErr: "divide by zero"},
+	}
+
+	for _, c := range cases {
+		got, tp, err := run(t, c.exp, params)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%q: got %v, %v, want an error containing %q", c.exp, got, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if got != c.want || tp != c.tp {
+			t.Errorf("%q: got %#v (%v), want %#v (%v)", c.exp, got, tp, c.want, c.tp)
+		}
+	}
+}
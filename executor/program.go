@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Program is a compiled expression. It is immutable once it has been built, every evaluation keeps its
+// state in its own frame, so a single Program can be shared and evaluated from any number of goroutines.
+//
+// The abstract syntax tree is lowered into bytecode when the program is created, Eval runs the bytecode
+// on the virtual machine instead of walking the tree.
+type Program struct {
+	root *Node
+
+	code     []instruction
+	maxStack int
+
+	// the names of the parameters read by the expression
+	params []string
+	// what the missing parameters evaluate to
+	missing MissingPolicy
+	// the clock read by `now()`, time.Now when nil
+	clock Clock
+	// how the decimals are rounded
+	rounding RoundingMode
+}
+
+// Result is the outcome of an evaluation of a Program.
+type Result struct {
+	Value interface{}
+	Type  TypeFlags
+
+	// Missing lists the parameters read by the expression which have not been provided, including the ones
+	// which were not needed by the evaluation, such as the skipped operand of `&&`
+	Missing []string
+}
+
+// frame holds the state of a single evaluation of a Program.
+type frame struct {
+	parameters Parameters
+	missing    MissingPolicy
+	clock      Clock
+	rounding   RoundingMode
+
+	// the time read from the clock by the first call of `now()`, every call of an evaluation returns it
+	now    time.Time
+	nowSet bool
+}
+
+func newFrame(parameters Parameters) *frame {
+	if parameters == nil {
+		parameters = DummyParameters
+	}
+	return &frame{
+		parameters: parameters,
+	}
+}
+
+// currentTime returns the time of the evaluation, the clock is read once so that all the calls of `now()` in an
+// expression agree.
+func (f *frame) currentTime() time.Time {
+	if !f.nowSet {
+		clock := f.clock
+		if clock == nil {
+			clock = time.Now
+		}
+		f.now, f.nowSet = clock(), true
+	}
+	return f.now
+}
+
+func NewProgram(root *Node) (*Program, error) {
+	if root == nil {
+		return nil, errors.New("engine: empty expression")
+	}
+
+	e := &emitter{}
+	if err := e.lower(root); err != nil {
+		return nil, err
+	}
+	inferExpected(root, TypeNull)
+
+	return &Program{
+		root:     root,
+		code:     e.code,
+		maxStack: e.maxDepth,
+		params:   paramNames(root, make(map[string]bool), nil),
+	}, nil
+}
+
+// Root returns the root node of the abstract syntax tree of the program.
+func (p *Program) Root() *Node {
+	return p.root
+}
+
+// Params returns the names of the parameters read by the expression, in the order they first appear.
+func (p *Program) Params() []string {
+	return append([]string(nil), p.params...)
+}
+
+// WithMissingPolicy returns a copy of the program whose missing parameters evaluate according to the policy, the
+// program itself is left untouched. Programs are evaluated with MissingStrict by default.
+func (p *Program) WithMissingPolicy(policy MissingPolicy) *Program {
+	cp := *p
+	cp.missing = policy
+	return &cp
+}
+
+// WithClock returns a copy of the program whose `now()` reads the given clock, the program itself is left
+// untouched. Programs read time.Now by default, a fixed clock makes the evaluations deterministic:
+//
+//	program.WithClock(func() time.Time { return time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC) })
+func (p *Program) WithClock(clock Clock) *Program {
+	cp := *p
+	cp.clock = clock
+	return &cp
+}
+
+// WithRounding returns a copy of the program whose decimals are rounded according to the mode, by `/`, `**` and
+// `round()`, the program itself is left untouched. Programs round with RoundHalfUp by default.
+func (p *Program) WithRounding(mode RoundingMode) *Program {
+	cp := *p
+	cp.rounding = mode
+	return &cp
+}
+
+// Eval evaluates the program with the given parameters and returns the result together with its type.
+func (p *Program) Eval(parameters Parameters) (interface{}, TypeFlags, error) {
+	f := newFrame(parameters)
+	f.missing = p.missing
+	f.clock = p.clock
+	f.rounding = p.rounding
+	ret, err := p.run(f)
+	if err != nil {
+		return nil, TypeNull, err
+	}
+	return ret.val, ret.tp, nil
+}
+
+// Evaluate evaluates the program like Eval, and reports all the parameters which have not been provided. The
+// missing parameters are reported whether the evaluation fails or not.
+func (p *Program) Evaluate(parameters Parameters) (Result, error) {
+	f := newFrame(parameters)
+	f.missing = p.missing
+	f.clock = p.clock
+	f.rounding = p.rounding
+
+	var result Result
+	for _, name := range p.params {
+		var missing *MissingParameterError
+		if _, err := f.parameters.Get(name); errors.As(err, &missing) {
+			result.Missing = append(result.Missing, name)
+		}
+	}
+
+	ret, err := p.run(f)
+	if err != nil {
+		return result, err
+	}
+	result.Value, result.Type = ret.val, ret.tp
+	return result, nil
+}
+
+func (p *Program) PrintSvg(name string) {
+	p.root.PrintSvg(name)
+}
+
+// Disassemble returns the bytecode of the program in a human readable form.
+func (p *Program) Disassemble() string {
+	var sb strings.Builder
+	for pc, ins := range p.code {
+		fmt.Fprintf(&sb, "%4d  %v\n", pc, ins)
+	}
+	return sb.String()
+}
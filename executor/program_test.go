@@ -0,0 +1,133 @@
+package executor_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func TestProgram_EvalReusable(t *testing.T) {
+	program := compile(t, `a + 1`)
+	for i := int64(0); i < 3; i++ {
+		got, tp, err := program.Eval(executor.MapParameters{"a": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != i+1 || tp != executor.TypeInteger {
+			t.Errorf("a=%d: got %v (%v), want %d", i, got, tp, i+1)
+		}
+	}
+
+	literal := compile(t, `7`)
+	for i := 0; i < 2; i++ {
+		if got, _, _ := literal.Eval(nil); got != int64(7) {
+			t.Errorf("literal was overwritten by a previous evaluation: %v", got)
+		}
+	}
+}
+
+func TestProgram_EvalConcurrent(t *testing.T) {
+	program := compile(t, `uid % 7 == 3 && name + "!" == expect || uid < 0`)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 64; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				uid := int64(g*1000 + i)
+				name := fmt.Sprintf("user-%d", uid)
+				params := executor.MapParameters{"uid": uid, "name": name, "expect": name + "!"}
+				got, _, err := program.Eval(params)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if want := uid%7 == 3; got != want {
+					errs <- fmt.Errorf("uid=%d: got %v, want %v", uid, got, want)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestProgram_MissingPolicy(t *testing.T) {
+	params := executor.MapParameters{"city": "sh"}
+	cases := []struct {
+		exp     string
+		policy  executor.MissingPolicy
+		want    interface{}
+		err     string
+		missing []string
+	}{
+		{exp: `age > 18 && city == "sh"`, policy: executor.MissingStrict, err: "No parameter 'age' found",
+			missing: []string{"age"}},
+		{exp: `age > 18 && city == "sh"`, policy: executor.MissingAsNull, want: false, missing: []string{"age"}},
+		{exp: `age > 18 || city == "sh"`, policy: executor.MissingAsDefault, want: true, missing: []string{"age"}},
+		{exp: `age + 1`, policy: executor.MissingAsDefault, want: int64(1), missing: []string{"age"}},
+		{exp: `age + 1`, policy: executor.MissingAsNull, want: nil, missing: []string{"age"}},
+		{exp: `name == "alice"`, policy: executor.MissingAsDefault, want: false, missing: []string{"name"}},
+		{exp: `name + "!"`, policy: executor.MissingAsDefault, want: "!", missing: []string{"name"}},
+		{exp: `!vip && upper(level) == ""`, policy: executor.MissingAsDefault, want: true,
+			missing: []string{"vip", "level"}},
+		{exp: `contains(name, "a")`, policy: executor.MissingAsDefault, want: false, missing: []string{"name"}},
+		{exp: `len(tags)`, policy: executor.MissingAsDefault,
+			err: "type mismatch for function [len]: arg 1='Null', expected 'string|list|map'", missing: []string{"tags"}},
+		{exp: `city in cities`, policy: executor.MissingAsDefault, want: false, missing: []string{"cities"}},
+		{exp: `vip ? rate : 1.5`, policy: executor.MissingAsDefault, want: 1.5, missing: []string{"vip", "rate"}},
+		{exp: `x == y`, policy: executor.MissingAsDefault, want: true, missing: []string{"x", "y"}},
+		{exp: `score ?? 5`, policy: executor.MissingAsDefault, want: int64(5), missing: []string{"score"}},
+		// every missing parameter is reported, not only the first one encountered
+		{exp: `city == "bj" && (a > b || c)`, policy: executor.MissingStrict, want: false,
+			missing: []string{"a", "b", "c"}},
+		{exp: `city + "!"`, policy: executor.MissingStrict, want: "sh!"},
+	}
+
+	for _, c := range cases {
+		program := compile(t, c.exp).WithMissingPolicy(c.policy)
+		result, err := program.Evaluate(params)
+		if !reflect.DeepEqual(result.Missing, c.missing) {
+			t.Errorf("%q (%v): missing %v, want %v", c.exp, c.policy, result.Missing, c.missing)
+		}
+		if c.err != "" {
+			if err == nil || message(err) != c.err {
+				t.Errorf("%q (%v): got error %v, want %q", c.exp, c.policy, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q (%v): unexpected error: %v", c.exp, c.policy, err)
+			continue
+		}
+		if result.Value != c.want {
+			t.Errorf("%q (%v): got %#v, want %#v", c.exp, c.policy, result.Value, c.want)
+		}
+	}
+}
+
+func TestProgram_WithMissingPolicy(t *testing.T) {
+	strict := compile(t, `age > 18`)
+	lenient := strict.WithMissingPolicy(executor.MissingAsDefault)
+
+	if got, _, err := lenient.Eval(nil); err != nil || got != false {
+		t.Errorf("lenient program: got %v, %v", got, err)
+	}
+	if _, _, err := strict.Eval(nil); err == nil {
+		t.Errorf("the policy of the original program has been changed")
+	}
+
+	if got := compile(t, `a + b * a > len(c)`).Params(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("params: got %v", got)
+	}
+}
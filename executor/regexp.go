@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// maxCachedRegexps bounds the number of patterns coming from parameters that are kept compiled.
+const maxCachedRegexps = 256
+
+// regexpCache holds the compiled patterns which are only known at run time, so that a pattern shared by many
+// evaluations is compiled once. It is emptied when it is full.
+var regexpCache = struct {
+	sync.RWMutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression '%s': %v", pattern, err)
+	}
+	return re, nil
+}
+
+// regexp returns the pattern of a MATCH or NOTMATCH node, compiled at build time when it is a literal.
+func (n *Node) regexp(pattern value) (*regexp.Regexp, error) {
+	if re, ok := n.value.(*regexp.Regexp); ok {
+		return re, nil
+	}
+
+	source := pattern.val.(string)
+	regexpCache.RLock()
+	re, exist := regexpCache.patterns[source]
+	regexpCache.RUnlock()
+	if exist {
+		return re, nil
+	}
+
+	re, err := compileRegexp(source)
+	if err != nil {
+		return nil, fmt.Errorf("engine: %v", err)
+	}
+
+	regexpCache.Lock()
+	if len(regexpCache.patterns) >= maxCachedRegexps {
+		regexpCache.patterns = make(map[string]*regexp.Regexp)
+	}
+	regexpCache.patterns[source] = re
+	regexpCache.Unlock()
+	return re, nil
+}
@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Span locates the source of a node in the expression, from the character at Start up to the one before End. The
+// offsets are counted in characters like the positions of the tokens.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// IsZero reports whether the span is unknown, such as the one of a node which is not built from an expression.
+func (s Span) IsZero() bool {
+	return s.End == 0
+}
+
+// EvalError is an error of the evaluation located in the expression, such as a type mismatch, a division by zero
+// or a missing parameter. It is reported by the innermost node which fails, and wraps the error of its operator or
+// function.
+type EvalError struct {
+	Err  error
+	Span Span
+	// Text is the source of the failing sub-expression, it is empty when the source is unknown
+	Text string
+}
+
+func (e *EvalError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("%v at position %d", e.Err, e.Span.Start)
+	}
+	return fmt.Sprintf("%v in `%s` at position %d", e.Err, e.Text, e.Span.Start)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON renders the error with its location, e.g.
+//
+//	{"message":"divide by zero","start":4,"end":9,"text":"a / b"}
+func (e *EvalError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message string `json:"message"`
+		Span
+		Text string `json:"text"`
+	}{e.Err.Error(), e.Span, e.Text})
+}
+
+// Span returns the part of the expression the node is built from.
+func (n *Node) Span() Span {
+	return n.span
+}
+
+// Text returns the source of the node, or an empty string if it is unknown.
+func (n *Node) Text() string {
+	return n.text
+}
+
+// SetSpan records the part of the expression the node is built from, the runtime errors of the node are located
+// there.
+func (n *Node) SetSpan(span Span, text string) {
+	n.span, n.text = span, text
+}
+
+// locate wraps the error of the evaluation of the node into an *EvalError, unless a node below has already
+// located it or the span of the node is unknown.
+func (n *Node) locate(err error) error {
+	var located *EvalError
+	if n.span.IsZero() || errors.As(err, &located) {
+		return err
+	}
+	return &EvalError{Err: err, Span: n.span, Text: n.text}
+}
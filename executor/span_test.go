@@ -0,0 +1,65 @@
+package executor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func TestEvalError(t *testing.T) {
+	params := executor.MapParameters{"age": 20, "city": "sh", "count": 0, "user": map[string]interface{}{"age": 30}}
+	cases := []struct {
+		exp  string
+		err  string
+		span executor.Span
+	}{
+		{exp: `age > 18 && city + 1 > 2`, span: executor.Span{Start: 12, End: 20},
+			err: "type mismatch for operator [+]: left='string', right='int' in `city + 1` at position 12"},
+		{exp: `age + 1 > 0 && 100 / count > 1`, span: executor.Span{Start: 15, End: 26},
+			err: "engine: number divide by zero in `100 / count` at position 15"},
+		{exp: "age > 18 &&\n  score > 60", span: executor.Span{Start: 14, End: 19},
+			err: "No parameter 'score' found in `score` at position 14"},
+		{exp: `user.name == "x"`, span: executor.Span{Start: 0, End: 9},
+			err: "No member 'name' found in `user.name` at position 0"},
+		{exp: `upper(city) + (age * city)`, span: executor.Span{Start: 15, End: 25},
+			err: "type mismatch for operator [*]: left='int', right='string' in `age * city` at position 15"},
+		{exp: `len(age)`, span: executor.Span{Start: 0, End: 8},
+			err: "type mismatch for function [len]: arg 1='int', expected 'string|list|map' in `len(age)` at position 0"},
+	}
+
+	for _, c := range cases {
+		program := compile(t, c.exp)
+		// the vm and the tree walk locate the same node
+		for _, eval := range []func(executor.Parameters) (interface{}, executor.TypeFlags, error){
+			program.Eval, program.Root().Eval,
+		} {
+			_, _, err := eval(params)
+			var evalErr *executor.EvalError
+			if !errors.As(err, &evalErr) {
+				t.Errorf("%q: got error %v, want an EvalError", c.exp, err)
+				continue
+			}
+			if err.Error() != c.err || evalErr.Span != c.span {
+				t.Errorf("%q: got error %v at %+v, want %q at %+v", c.exp, err, evalErr.Span, c.err, c.span)
+			}
+		}
+	}
+
+	// the typed errors are still found through the location
+	_, _, err := compile(t, `age > 18 && vip`).Eval(params)
+	var missing *executor.MissingParameterError
+	if !errors.As(err, &missing) || missing.Name != "vip" {
+		t.Errorf("got error %v, want a MissingParameterError", err)
+	}
+}
+
+func TestEvalError_MarshalJSON(t *testing.T) {
+	_, _, err := compile(t, `a / b`).Eval(executor.MapParameters{"a": 1, "b": 0})
+	b, _ := json.Marshal(err)
+	want := `{"message":"engine: number divide by zero","start":0,"end":5,"text":"a / b"}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
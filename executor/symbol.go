@@ -2,125 +2,129 @@ package executor
 
 import (
 	"fmt"
-	"github.com/qimengxingyuan/young_engine/token"
 )
 
 type Symbol int
 
 const (
-	VALUE    Symbol = iota
-	LITERAL         // var string int float bool
-	NOOP            // noop
-	EQ              // ==
-	NEQ             // !=
-	GT              // >
-	LT              // <
-	GTE             // >=
-	LTE             // <=
-	AND             // &&
-	OR              // ||
-	PLUS            // +
-	MINUS           // -
-	MULTIPLY        // *
-	DIVIDE          // /s
-	MODULUS         // %
-	INVERT          // ！
-	POSITIVE        // +
-	NEGATIVE        // -
+	VALUE       Symbol = iota
+	LITERAL            // var string int float bool
+	EQ                 // ==
+	NEQ                // !=
+	GT                 // >
+	LT                 // <
+	GTE                // >=
+	LTE                // <=
+	AND                // &&
+	OR                 // ||
+	PLUS               // +
+	MINUS              // -
+	MULTIPLY           // *
+	DIVIDE             // /s
+	MODULUS            // %
+	INVERT             // ！
+	POSITIVE           // +
+	NEGATIVE           // -
+	IN                 // in
+	NOTIN              // not in
+	LIST               // [1, 2, 3]
+	MEMBER             // user.age
+	INDEX              // items[0]
+	CALL               // len(name)
+	MATCH              // =~
+	NOTMATCH           // !~
+	CONDITIONAL        // vip ? 0.8 : 1
+	COALESCE           // score ?? 0
+	BITAND             // &
+	BITOR              // |
+	BITXOR             // ^
+	BITNOT             // ~
+	SHIFTLEFT          // <<
+	SHIFTRIGHT         // >>
+	POWER              // **
 )
 
 const (
 	binaryErrFmt = "type mismatch for operator [%s]: left='%s', right='%s'"
 	unaryErrFmt  = "type mismatch for operator [%s]: right='%s'"
+	condErrFmt   = "type mismatch for operator [?:]: condition='%s', expected 'boolean'"
+	branchErrFmt = "type mismatch for operator [?:]: branches '%s' and '%s' are not compatible"
 )
 
 var (
-	MultiKindsToSymbol = map[token.Kind]Symbol{
-		token.Multiply: MULTIPLY,
-		token.Divide:   DIVIDE,
-		token.Modulus:  MODULUS,
-	}
-
-	AddKindsToSymbol = map[token.Kind]Symbol{
-		token.Addition:    PLUS,
-		token.Subtraction: MINUS,
-	}
-
-	CompareKindsToSymbol = map[token.Kind]Symbol{
-		token.GreaterThan:  GT,
-		token.GreaterEqual: GTE,
-		token.LessThan:     LT,
-		token.LessEqual:    LTE,
-		token.Equal:        EQ,
-		token.NotEqual:     NEQ,
-	}
-
-	OrKindsToSymbol = map[token.Kind]Symbol{
-		token.Or: OR,
-	}
-
-	AndKindsToSymbol = map[token.Kind]Symbol{
-		token.And: AND,
-	}
-
-	NotKindsToSymbol = map[token.Kind]Symbol{
-		token.Not: INVERT,
-	}
-
-	needFixedSymbol = map[Symbol]bool{
-		POSITIVE: true,
-		NEGATIVE: true,
-	}
-
 	symbolToOperator = map[Symbol]operator{
-		VALUE:    parameterOperator,
-		LITERAL:  literalOperator,
-		NOOP:     noopOperator,
-		EQ:       equalOperator,
-		NEQ:      notEqualOperator,
-		GT:       gtOperator,
-		LT:       ltOperator,
-		GTE:      gteOperator,
-		LTE:      lteOperator,
-		AND:      andOperator,
-		OR:       orOperator,
-		PLUS:     addOperator,
-		MINUS:    subtractOperator,
-		MULTIPLY: multiplyOperator,
-		DIVIDE:   divideOperator,
-		MODULUS:  modulusOperator,
-		INVERT:   invertOperator,
-		NEGATIVE: negateOperator,
-		POSITIVE: noopOperator,
+		VALUE:      parameterOperator,
+		LITERAL:    literalOperator,
+		EQ:         equalOperator,
+		NEQ:        notEqualOperator,
+		GT:         gtOperator,
+		LT:         ltOperator,
+		GTE:        gteOperator,
+		LTE:        lteOperator,
+		AND:        andOperator,
+		OR:         orOperator,
+		PLUS:       addOperator,
+		MINUS:      subtractOperator,
+		MULTIPLY:   multiplyOperator,
+		DIVIDE:     divideOperator,
+		MODULUS:    modulusOperator,
+		INVERT:     invertOperator,
+		NEGATIVE:   negateOperator,
+		POSITIVE:   noopOperator,
+		IN:         inOperator,
+		NOTIN:      notInOperator,
+		MEMBER:     indexOperator,
+		INDEX:      indexOperator,
+		MATCH:      matchOperator,
+		NOTMATCH:   notMatchOperator,
+		BITAND:     bitAndOperator,
+		BITOR:      bitOrOperator,
+		BITXOR:     bitXorOperator,
+		BITNOT:     bitNotOperator,
+		SHIFTLEFT:  shiftLeftOperator,
+		SHIFTRIGHT: shiftRightOperator,
+		POWER:      powerOperator,
 	}
 
 	symbolToTypeChecker = map[Symbol]typeChecker{
 		VALUE:    nil,
 		LITERAL:  nil,
-		NOOP:     nil,
 		EQ:       matchChecker,
 		NEQ:      matchChecker,
-		GT:       numberOrStringChecker,
-		LT:       numberOrStringChecker,
-		GTE:      numberOrStringChecker,
-		LTE:      numberOrStringChecker,
+		GT:       orderChecker,
+		LT:       orderChecker,
+		GTE:      orderChecker,
+		LTE:      orderChecker,
 		AND:      doubleBoolChecker,
 		OR:       doubleBoolChecker,
-		PLUS:     numberOrStringChecker,
-		MINUS:    doubleNumberChecker,
-		MULTIPLY: doubleNumberChecker,
-		DIVIDE:   doubleNumberChecker,
+		PLUS:     addChecker,
+		MINUS:    subtractChecker,
+		MULTIPLY: multiplyChecker,
+		DIVIDE:   divideChecker,
 		MODULUS:  doubleNumberChecker,
 		INVERT:   singleBoolChecker,
-		NEGATIVE: singleNumberChecker,
-		POSITIVE: singleNumberChecker,
+		NEGATIVE: signChecker,
+		POSITIVE: signChecker,
+		IN:       memberChecker,
+		NOTIN:    memberChecker,
+		MEMBER:   indexChecker,
+		INDEX:    indexChecker,
+		MATCH:    doubleStringChecker,
+		NOTMATCH: doubleStringChecker,
+		// the checker of CONDITIONAL compares the types of the branches, the condition must be a boolean
+		CONDITIONAL: branchChecker,
+		BITAND:      doubleIntegerChecker,
+		BITOR:       doubleIntegerChecker,
+		BITXOR:      doubleIntegerChecker,
+		BITNOT:      singleIntegerChecker,
+		SHIFTLEFT:   doubleIntegerChecker,
+		SHIFTRIGHT:  doubleIntegerChecker,
+		POWER:       powerChecker,
 	}
 )
 
 func (s Symbol) String() string {
 	switch s {
-	case NOOP:
-		return "()"
 	case VALUE:
 		return "VALUE"
 	case EQ:
@@ -151,6 +155,38 @@ func (s Symbol) String() string {
 		return "%"
 	case INVERT:
 		return "!"
+	case IN:
+		return "in"
+	case NOTIN:
+		return "not in"
+	case LIST, INDEX:
+		return "[]"
+	case MEMBER:
+		return "."
+	case CALL:
+		return "()"
+	case MATCH:
+		return "=~"
+	case NOTMATCH:
+		return "!~"
+	case CONDITIONAL:
+		return "?:"
+	case COALESCE:
+		return "??"
+	case BITAND:
+		return "&"
+	case BITOR:
+		return "|"
+	case BITXOR:
+		return "^"
+	case BITNOT:
+		return "~"
+	case SHIFTLEFT:
+		return "<<"
+	case SHIFTRIGHT:
+		return ">>"
+	case POWER:
+		return "**"
 	}
 	return ""
 }
@@ -169,14 +205,16 @@ func (s Symbol) getTypeChecker() typeChecker {
 	return nil
 }
 
-func (s Symbol) formatTypeError(left, right *Node) error {
+func (s Symbol) formatTypeError(left, right TypeFlags) error {
 	switch s {
-	case PLUS, MINUS, MULTIPLY, DIVIDE, MODULUS:
-		return fmt.Errorf(binaryErrFmt, s.String(), left.tp.String(), right.tp.String())
-	case GT, GTE, LT, LTE, EQ, NEQ, AND, OR:
-		return fmt.Errorf(binaryErrFmt, s.String(), left.tp.String(), right.tp.String())
-	case NEGATIVE, POSITIVE, INVERT:
-		return fmt.Errorf(unaryErrFmt, s.String(), right.tp.String())
+	case PLUS, MINUS, MULTIPLY, DIVIDE, MODULUS, POWER, BITAND, BITOR, BITXOR, SHIFTLEFT, SHIFTRIGHT:
+		return fmt.Errorf(binaryErrFmt, s.String(), left.String(), right.String())
+	case GT, GTE, LT, LTE, EQ, NEQ, AND, OR, IN, NOTIN, MEMBER, INDEX, MATCH, NOTMATCH:
+		return fmt.Errorf(binaryErrFmt, s.String(), left.String(), right.String())
+	case CONDITIONAL:
+		return fmt.Errorf(branchErrFmt, left.String(), right.String())
+	case NEGATIVE, POSITIVE, INVERT, BITNOT:
+		return fmt.Errorf(unaryErrFmt, s.String(), right.String())
 	default:
 		return fmt.Errorf("type error for %v", s.String())
 	}
@@ -0,0 +1,241 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationOverflowErrFmt is the error of an arithmetic on times and durations whose result is out of the range of
+// the durations, about 292 years.
+const durationOverflowErrFmt = "engine: duration overflow: %v %v %v"
+
+// Clock returns the current time, it is read by `now()`.
+type Clock func() time.Time
+
+// durationUnits are the units of the duration literals and of ParseDuration, from the largest to the smallest.
+var durationUnits = map[string]time.Duration{
+	"d":  24 * time.Hour,
+	"h":  time.Hour,
+	"m":  time.Minute,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// ParseTime parses a date `2024-01-01`, which is midnight UTC, or a RFC 3339 time `2024-01-01T08:30:00+08:00`.
+func ParseTime(s string) (time.Time, error) {
+	layout := time.RFC3339Nano
+	if !strings.Contains(s, "T") {
+		layout = "2006-01-02"
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s', expected a date like 2024-01-01 or a RFC 3339 time "+
+			"like 2024-01-01T08:30:00Z", s)
+	}
+	return t, nil
+}
+
+// ParseDuration parses a sequence of decimal numbers followed by a unit, such as `7d`, `36h`, `1h30m` or `1.5s`.
+// The units are d (24 hours), h, m, s, ms, us and ns.
+func ParseDuration(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid duration '%s'", s)
+	overflow := fmt.Errorf("duration '%s' is out of range", s)
+	if s == "" {
+		return 0, invalid
+	}
+
+	var total int64
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && ('0' <= rest[i] && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(rest) && !('0' <= rest[j] && rest[j] <= '9' || rest[j] == '.') {
+			j++
+		}
+
+		unit, known := durationUnits[rest[i:j]]
+		if !known {
+			return 0, invalid
+		}
+		d, err := scaleDecimal(rest[:i], int64(unit))
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, overflow
+			}
+			return 0, invalid
+		}
+		var ok bool
+		if total, ok = addInt(total, d); !ok {
+			return 0, overflow
+		}
+		rest = rest[j:]
+	}
+	return time.Duration(total), nil
+}
+
+// scaleDecimal returns the decimal number n, such as `1.15`, multiplied by the unit. The integer part is scaled
+// exactly, the fraction is rounded down to the unit like time.ParseDuration does, so that `1.15h` is 69 minutes
+// rather than a float a little below. It returns strconv.ErrRange if the result overflows.
+func scaleDecimal(n string, unit int64) (int64, error) {
+	whole, frac := n, ""
+	if dot := strings.IndexByte(n, '.'); dot >= 0 {
+		whole, frac = n[:dot], n[dot+1:]
+	}
+	if whole == "" && frac == "" || strings.IndexByte(frac, '.') >= 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	var ret int64
+	if whole != "" {
+		i, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return 0, strconv.ErrRange
+		}
+		var ok bool
+		if ret, ok = mulInt(i, unit); !ok {
+			return 0, strconv.ErrRange
+		}
+	}
+
+	// the digits beyond the precision of an int64 do not change the result
+	var f int64
+	scale := 1.0
+	for _, ch := range frac {
+		if f > (math.MaxInt64-9)/10 {
+			break
+		}
+		f = f*10 + int64(ch-'0')
+		scale *= 10
+	}
+	ret, ok := addInt(ret, int64(float64(f)*(float64(unit)/scale)))
+	if !ok {
+		return 0, strconv.ErrRange
+	}
+	return ret, nil
+}
+
+// execTimeBinOp executes the arithmetic and the comparisons involving a time or a duration, the type checker of
+// the operator has ensured that the operands are compatible:
+//   - time - time is the duration between them, time + duration, duration + time and time - duration are times
+//   - durations are added to and subtracted from each other, multiplied and divided by numbers, and a duration
+//     divided by a duration is a float
+//   - times are compared to times and durations to durations
+func execTimeBinOp(l, r value, op Symbol) (interface{}, TypeFlags, error) {
+	switch {
+	case l.tp.IsTime() && r.tp.IsTime():
+		t1, t2 := l.val.(time.Time), r.val.(time.Time)
+		if op == MINUS {
+			// Sub saturates instead of overflowing
+			d := t1.Sub(t2)
+			if !t2.Add(d).Equal(t1) {
+				return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, t1, op, t2)
+			}
+			return d, TypeDuration, nil
+		}
+		// compare the instants, regardless of the location of the times
+		return execNumberBinOp(value{val: int64(t1.Sub(t2)), tp: TypeInteger}, value{val: int64(0), tp: TypeInteger}, op)
+	case l.tp.IsTime():
+		t, d := l.val.(time.Time), r.val.(time.Duration)
+		if op == MINUS {
+			if d == math.MinInt64 {
+				return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, t, op, d)
+			}
+			d = -d
+		}
+		return t.Add(d), TypeTime, nil
+	case r.tp.IsTime():
+		return r.val.(time.Time).Add(l.val.(time.Duration)), TypeTime, nil
+	case l.tp.IsDuration() && r.tp.IsDuration():
+		d1, d2 := l.val.(time.Duration), r.val.(time.Duration)
+		switch op {
+		case PLUS:
+			d, ok := addInt(int64(d1), int64(d2))
+			if !ok {
+				return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, d1, op, d2)
+			}
+			return time.Duration(d), TypeDuration, nil
+		case MINUS:
+			d, ok := subInt(int64(d1), int64(d2))
+			if !ok {
+				return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, d1, op, d2)
+			}
+			return time.Duration(d), TypeDuration, nil
+		case DIVIDE:
+			if d2 == 0 {
+				return nil, TypeNull, divideZeroErr
+			}
+			return float64(d1) / float64(d2), TypeFloat, nil
+		}
+		return execNumberBinOp(value{val: int64(d1), tp: TypeInteger}, value{val: int64(d2), tp: TypeInteger}, op)
+	case l.tp.IsDuration():
+		return scaleDuration(l.val.(time.Duration), r, op)
+	case r.tp.IsDuration() && op == MULTIPLY:
+		return scaleDuration(r.val.(time.Duration), l, op)
+	}
+	return nil, TypeNull, errors.New("engine: unreachable code")
+}
+
+// scaleDuration multiplies or divides the duration by the number n.
+func scaleDuration(d time.Duration, n value, op Symbol) (interface{}, TypeFlags, error) {
+	if i, isInt := n.val.(int64); isInt {
+		if op == MULTIPLY {
+			ret, ok := mulInt(int64(d), i)
+			if !ok {
+				return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, d, op, i)
+			}
+			return time.Duration(ret), TypeDuration, nil
+		}
+		if i == 0 {
+			return nil, TypeNull, divideZeroErr
+		}
+		if d == math.MinInt64 && i == -1 {
+			return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, d, op, i)
+		}
+		return d / time.Duration(i), TypeDuration, nil
+	}
+
+	x := int2float(n.val)
+	var ret float64
+	if op == MULTIPLY {
+		ret = float64(d) * x
+	} else {
+		if x == 0 {
+			return nil, TypeNull, divideZeroErr
+		}
+		ret = float64(d) / x
+	}
+	// the conversion of a float out of range, or NaN, to an integer is undefined
+	if !(ret >= math.MinInt64 && ret < math.MaxInt64) {
+		return nil, TypeNull, fmt.Errorf(durationOverflowErrFmt, d, op, x)
+	}
+	return time.Duration(ret), TypeDuration, nil
+}
+
+// temporalType returns the type of the result of an arithmetic operator involving a time or a duration, it
+// reports false if the operands are not compatible.
+func temporalType(op Symbol, left, right TypeFlags) (TypeFlags, bool) {
+	switch {
+	case op == MINUS && left.IsTime() && right.IsTime():
+		return TypeDuration, true
+	case (op == PLUS || op == MINUS) && left.IsTime() && right.IsDuration(), op == PLUS && left.IsDuration() && right.IsTime():
+		return TypeTime, true
+	case (op == PLUS || op == MINUS) && left.IsDuration() && right.IsDuration():
+		return TypeDuration, true
+	case op == DIVIDE && left.IsDuration() && right.IsDuration():
+		return TypeFloat, true
+	case (op == MULTIPLY || op == DIVIDE) && left.IsDuration() && right.IsNumber(),
+		op == MULTIPLY && left.IsNumber() && right.IsDuration():
+		return TypeDuration, true
+	}
+	return TypeNull, false
+}
@@ -0,0 +1,119 @@
+package executor_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+func TestTimeOperator(t *testing.T) {
+	clock := func() time.Time { return time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC) } // a friday
+	params := executor.MapParameters{
+		"created": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		"ttl":     90 * time.Minute,
+		"day":     "2024-02-01",
+		"ts":      1704067200,
+	}
+
+	cases := []struct {
+		exp     string
+		want    interface{}
+		wantErr bool
+	}{
+		{exp: `@2024-01-01T08:00:00+08:00`, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{exp: `@2024-01-01T08:00:00+08:00 == @2024-01-01`, want: true},
+		{exp: `created == @2024-01-01T00:00:00Z`, want: true},
+		{exp: `7d`, want: 7 * 24 * time.Hour},
+		{exp: `1h30m == ttl`, want: true},
+		{exp: `1.5s + 500ms`, want: 2 * time.Second},
+		{exp: `1.15h == 69m`, want: true},
+		{exp: `1.000000000000000000001h`, want: time.Hour},
+		{exp: `9223372036854775807ns`, want: time.Duration(math.MaxInt64)},
+		{exp: `now() - created > 30d`, want: true},
+		{exp: `now() - created`, want: (31+29+14)*24*time.Hour + 10*time.Hour + 30*time.Minute},
+		{exp: `hour(now()) >= 9 && hour(now()) < 18`, want: true},
+		{exp: `weekday(now())`, want: int64(5)},
+		{exp: `year(created) * 100 + month(created) + day(created)`, want: int64(202402)},
+		{exp: `minute(now())`, want: int64(30)},
+		{exp: `created + 7d`, want: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{exp: `7d + created`, want: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{exp: `created - 1d < @2024-01-01`, want: true},
+		{exp: `@2024-01-02-1d == created`, want: true},
+		{exp: `ttl * 2`, want: 3 * time.Hour},
+		{exp: `0.5 * ttl`, want: 45 * time.Minute},
+		{exp: `ttl / 2`, want: 45 * time.Minute},
+		{exp: `ttl / 30m`, want: 3.0},
+		{exp: `-ttl < 0s`, want: true},
+		{exp: `ttl - 1h >= 30m`, want: true},
+		{exp: `now() - now()`, want: time.Duration(0)},
+		{exp: `time(day) + 1d`, want: time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{exp: `time(ts) == created && unix(created) == ts`, want: true},
+		{exp: `duration("2d") == 48h && duration(60) == 1m`, want: true},
+		{exp: `string(created) + " " + string(ttl)`, want: "2024-01-01T00:00:00Z 1h30m0s"},
+		{exp: `created in [@2023-12-31, @2024-01-01]`, want: true},
+		{exp: `created + created`, wantErr: true},
+		{exp: `ttl * ttl`, wantErr: true},
+		{exp: `created > ttl`, wantErr: true},
+		{exp: `ttl / 0`, wantErr: true},
+		{exp: `ttl + 1`, wantErr: true},
+		{exp: `time("2024-13-01")`, wantErr: true},
+		{exp: `duration("7x")`, wantErr: true},
+		{exp: `duration(9223372036854775807)`, wantErr: true},
+		{exp: `9223372036854775807 * 1d`, wantErr: true},
+		{exp: `ttl * 1e300`, wantErr: true},
+		{exp: `ttl / 1e-300`, wantErr: true},
+		{exp: `2562047h + 2562047h`, wantErr: true},
+		{exp: `duration("9223372036854775808ns")`, wantErr: true},
+		{exp: `duration("106751d24h")`, wantErr: true},
+		{exp: `created + 9223372036854775807 * 1ns`, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(math.MaxInt64)},
+		{exp: `@2024-01-01 - @1700-01-01`, wantErr: true},
+		{exp: `created - (-2562047h - 2562047h)`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		program, err := build(t, c.exp)
+		var got interface{}
+		if err == nil {
+			got, _, err = program.WithClock(clock).Eval(params)
+		}
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.exp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.exp, err)
+			continue
+		}
+		if want, ok := c.want.(time.Time); ok {
+			if !want.Equal(got.(time.Time)) {
+				t.Errorf("%q: got %v, want %v", c.exp, got, want)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %#v, want %#v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestProgram_WithClock(t *testing.T) {
+	program := compile(t, `now()`)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, tp, err := program.WithClock(func() time.Time { return at }).Eval(nil)
+	if err != nil || tp != executor.TypeTime || !got.(time.Time).Equal(at) {
+		t.Errorf("got %v (%v), %v, want %v", got, tp, err, at)
+	}
+
+	// now() is not folded at compile time, the program reads the wall clock by default
+	before := time.Now()
+	got, _, err = program.Eval(nil)
+	if err != nil || got.(time.Time).Before(before) {
+		t.Errorf("got %v, %v, want a time after %v", got, err, before)
+	}
+}
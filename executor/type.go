@@ -2,38 +2,96 @@ package executor
 
 import (
 	"encoding/json"
+	"math/big"
+	"reflect"
 	"strings"
+	"time"
 )
 
+// TypeFlags is the type of a value. Every type is a single bit so that a set of types, such as the types a
+// parameter of a function accepts, can be expressed as the union of them, e.g. TypeInteger | TypeFloat.
 type TypeFlags int
 
+// value is the result of evaluating a node, the go value together with its engine type.
+type value struct {
+	val interface{}
+	tp  TypeFlags
+}
+
 const (
-	TypeNull TypeFlags = iota
-	TypeBool
+	TypeNull TypeFlags = 0
+	TypeBool TypeFlags = 1 << (iota - 1)
 	TypeInteger
 	TypeFloat
 	TypeString
+	TypeList
+	TypeMap
+	TypeTime
+	TypeDuration
+	TypeDecimal
+
+	// TypeNumber is the set of numeric types
+	TypeNumber = TypeInteger | TypeFloat | TypeDecimal
+	// TypeAny is the set of all types except TypeNull
+	TypeAny = TypeBool | TypeInteger | TypeFloat | TypeString | TypeList | TypeMap | TypeTime | TypeDuration |
+		TypeDecimal
 )
 
+var typeNames = []struct {
+	tp   TypeFlags
+	name string
+}{
+	{TypeBool, "boolean"},
+	{TypeInteger, "int"},
+	{TypeFloat, "float"},
+	{TypeString, "string"},
+	{TypeList, "list"},
+	{TypeMap, "map"},
+	{TypeTime, "time"},
+	{TypeDuration, "duration"},
+	{TypeDecimal, "decimal"},
+}
+
 func (t TypeFlags) String() string {
-	switch t {
-	case TypeNull:
+	if t == TypeNull {
 		return "Null"
-	case TypeBool:
-		return "boolean"
-	case TypeFloat:
-		return "float"
-	case TypeString:
-		return "string"
-	case TypeInteger:
-		return "int"
-	default:
+	}
+	if t == TypeAny {
+		return "any"
+	}
+
+	names := make([]string, 0, 1)
+	for _, tn := range typeNames {
+		if t&tn.tp != 0 {
+			names = append(names, tn.name)
+		}
+	}
+	if len(names) == 0 || t&^TypeAny != 0 {
 		return "unknown type"
 	}
+	return strings.Join(names, "|")
+}
+
+// Accepts reports whether a value of type tp belongs to the set of types t.
+func (t TypeFlags) Accepts(tp TypeFlags) bool {
+	return tp != TypeNull && t&tp == tp
+}
+
+// IsSingle reports whether t is exactly one type rather than a set of types.
+func (t TypeFlags) IsSingle() bool {
+	return t != TypeNull && t&(t-1) == 0
 }
 
 func (t TypeFlags) IsNumber() bool {
-	return t == TypeFloat || t == TypeInteger
+	return t == TypeFloat || t == TypeInteger || t == TypeDecimal
+}
+
+func (t TypeFlags) IsInteger() bool {
+	return t == TypeInteger
+}
+
+func (t TypeFlags) IsDecimal() bool {
+	return t == TypeDecimal
 }
 
 func (t TypeFlags) IsString() bool {
@@ -48,6 +106,27 @@ func (t TypeFlags) IsNull() bool {
 	return t == TypeNull
 }
 
+func (t TypeFlags) IsList() bool {
+	return t == TypeList
+}
+
+func (t TypeFlags) IsMap() bool {
+	return t == TypeMap
+}
+
+func (t TypeFlags) IsTime() bool {
+	return t == TypeTime
+}
+
+func (t TypeFlags) IsDuration() bool {
+	return t == TypeDuration
+}
+
+// IsTemporal reports whether t is a time or a duration.
+func (t TypeFlags) IsTemporal() bool {
+	return t == TypeTime || t == TypeDuration
+}
+
 func getType(v interface{}) (interface{}, TypeFlags) {
 	val := castFixedPoint(v)
 	switch val.(type) {
@@ -59,11 +138,59 @@ func getType(v interface{}) (interface{}, TypeFlags) {
 		return val, TypeString
 	case bool:
 		return val, TypeBool
+	case []interface{}:
+		return castList(val)
+	case map[string]interface{}:
+		return val, TypeMap
+	case time.Time:
+		return val, TypeTime
+	case time.Duration:
+		return val, TypeDuration
+	case Decimal:
+		return val, TypeDecimal
 	default:
+		rv := reflect.ValueOf(val)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			return castList(val)
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				return castMap(rv), TypeMap
+			}
+		}
 		return v, TypeNull
 	}
 }
 
+// castMap converts a go map with string keys into a map[string]interface{}. Unlike lists the values are not
+// converted here, each one is converted when it is accessed.
+func castMap(rv reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m
+}
+
+// castList converts a go slice or array, such as []int or a decoded JSON array, into a list whose elements are
+// all of a supported type or null.
+func castList(v interface{}) (interface{}, TypeFlags) {
+	rv := reflect.ValueOf(v)
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		item := rv.Index(i).Interface()
+		elem, tp := getType(item)
+		if tp.IsNull() && item != nil {
+			return v, TypeNull
+		}
+		list[i] = elem
+	}
+	return list, TypeList
+}
+
+// castFixedPoint converts the go numbers into int64 or float64. The integers beyond the range of int64, such as
+// a large uint64 or *big.Int, are converted into a decimal without fraction rather than wrapping around.
 func castFixedPoint(value interface{}) interface{} {
 	switch v := value.(type) {
 	case uint8:
@@ -73,9 +200,9 @@ func castFixedPoint(value interface{}) interface{} {
 	case uint32:
 		return int64(v)
 	case uint64:
-		return int64(v)
+		return bigInteger(new(big.Int).SetUint64(v))
 	case uint:
-		return int64(v)
+		return bigInteger(new(big.Int).SetUint64(uint64(v)))
 	case int8:
 		return int64(v)
 	case int16:
@@ -90,14 +217,16 @@ func castFixedPoint(value interface{}) interface{} {
 		return float64(v)
 	case float64:
 		return v
+	case *big.Int:
+		return bigInteger(v)
 	case json.Number:
-		if strings.Contains(v.String(), ".") {
-			fv, _ := v.Float64()
-			return fv
-		} else {
-			iv, _ := v.Int64()
-			return iv
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, ok := new(big.Int).SetString(v.String(), 10); ok {
+				return bigInteger(i)
+			}
 		}
+		fv, _ := v.Float64()
+		return fv
 	}
 	return value
 }
@@ -1,29 +1,97 @@
 package executor
 
-type typeChecker func(left *Node, right *Node) bool
+type typeChecker func(left, right TypeFlags) bool
 
-// + > = < >= <=
-func numberOrStringChecker(left *Node, right *Node) bool {
-	return (left.tp.IsString() && right.tp.IsString()) || (left.tp.IsNumber() && right.tp.IsNumber())
+func numberOrStringChecker(left, right TypeFlags) bool {
+	return (left.IsString() && right.IsString()) || (left.IsNumber() && right.IsNumber())
 }
 
-// - * / %
-func doubleNumberChecker(left *Node, right *Node) bool {
-	return left.tp.IsNumber() && right.tp.IsNumber()
+// > >= < <=, times and durations are ordered too
+func orderChecker(left, right TypeFlags) bool {
+	return numberOrStringChecker(left, right) || (left == right && left.IsTemporal())
 }
 
-func matchChecker(left *Node, right *Node) bool {
-	return left.tp == right.tp
+// %
+func doubleNumberChecker(left, right TypeFlags) bool {
+	return left.IsNumber() && right.IsNumber()
 }
 
-func doubleBoolChecker(left *Node, right *Node) bool {
-	return left.tp.IsBool() && right.tp.IsBool()
+// +
+func addChecker(left, right TypeFlags) bool {
+	_, temporal := temporalType(PLUS, left, right)
+	return numberOrStringChecker(left, right) || temporal
 }
 
-func singleBoolChecker(left *Node, right *Node) bool {
-	return right.tp.IsBool()
+// -
+func subtractChecker(left, right TypeFlags) bool {
+	_, temporal := temporalType(MINUS, left, right)
+	return doubleNumberChecker(left, right) || temporal
 }
 
-func singleNumberChecker(left *Node, right *Node) bool {
-	return right.tp.IsNumber()
+// *
+func multiplyChecker(left, right TypeFlags) bool {
+	_, temporal := temporalType(MULTIPLY, left, right)
+	return doubleNumberChecker(left, right) || temporal
+}
+
+// /
+func divideChecker(left, right TypeFlags) bool {
+	_, temporal := temporalType(DIVIDE, left, right)
+	return doubleNumberChecker(left, right) || temporal
+}
+
+// **, a decimal is only raised to the power of an integer
+func powerChecker(left, right TypeFlags) bool {
+	if left.IsDecimal() || right.IsDecimal() {
+		return left.IsDecimal() && right.IsInteger()
+	}
+	return doubleNumberChecker(left, right)
+}
+
+// & | ^ << >>
+func doubleIntegerChecker(left, right TypeFlags) bool {
+	return left.IsInteger() && right.IsInteger()
+}
+
+// ~
+func singleIntegerChecker(left, right TypeFlags) bool {
+	return right.IsInteger()
+}
+
+// == !=, numbers of different types are compared by value, lists and maps element by element
+func matchChecker(left, right TypeFlags) bool {
+	return left == right || (left.IsNumber() && right.IsNumber())
+}
+
+func doubleBoolChecker(left, right TypeFlags) bool {
+	return left.IsBool() && right.IsBool()
+}
+
+func singleBoolChecker(left, right TypeFlags) bool {
+	return right.IsBool()
+}
+
+// unary + -
+func signChecker(left, right TypeFlags) bool {
+	return right.IsNumber() || right.IsDuration()
+}
+
+// in, not in
+func memberChecker(left, right TypeFlags) bool {
+	return right.IsList() && !left.IsList()
+}
+
+// . []
+func indexChecker(left, right TypeFlags) bool {
+	return (left.IsMap() && right.IsString()) || (left.IsList() && right == TypeInteger)
+}
+
+// =~ !~
+func doubleStringChecker(left, right TypeFlags) bool {
+	return left.IsString() && right.IsString()
+}
+
+// the branches of ?:, an int and a float are compatible, and null is compatible with any type
+func branchChecker(left, right TypeFlags) bool {
+	return left == right || (left.IsNumber() && right.IsNumber()) || left.IsNull() || right.IsNull()
 }
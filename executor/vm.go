@@ -0,0 +1,206 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+)
+
+// run executes the instructions of the program on a stack machine. Like the tree walk of Node.Eval the
+// right operand of `&&` and `||` is skipped as soon as the left operand decides the result, and only the selected
+// branch of `?:` is executed, and the right operand of `??` only when the left one is null. An error is located at
+// the node of the failing instruction.
+func (p *Program) run(f *frame) (value, error) {
+	var buf [16]value
+	stack := buf[:0]
+	if p.maxStack > len(buf) {
+		stack = make([]value, 0, p.maxStack)
+	}
+
+	for pc := 0; pc < len(p.code); pc++ {
+		ins := &p.code[pc]
+		switch ins.op {
+		case opLoadConst:
+			stack = append(stack, value{val: ins.node.value, tp: ins.node.tp})
+		case opLoadParam:
+			val, tp, err := parameterOperator(ins.node, value{}, value{}, f)
+			if err != nil {
+				return value{}, ins.node.locate(err)
+			}
+			stack = append(stack, value{val: val, tp: tp})
+		case opUnary:
+			top := len(stack) - 1
+			ret, ok := fastUnary(ins.node.symbol, stack[top])
+			if !ok {
+				var err error
+				ret, err = ins.node.apply(value{}, stack[top], f)
+				if err != nil {
+					return value{}, ins.node.locate(err)
+				}
+			}
+			stack[top] = ret
+		case opBinary:
+			top := len(stack) - 1
+			ret, ok := fastBinary(ins.node.symbol, stack[top-1], stack[top])
+			if !ok {
+				var err error
+				ret, err = ins.node.apply(stack[top-1], stack[top], f)
+				if err != nil {
+					return value{}, ins.node.locate(err)
+				}
+			}
+			stack = stack[:top]
+			stack[top-1] = ret
+		case opJumpIfFalse, opJumpIfTrue:
+			top := len(stack) - 1
+			left := stack[top]
+			if !left.tp.IsBool() {
+				return value{}, ins.node.locate(ins.node.symbol.formatTypeError(left.tp, ins.skipped))
+			}
+			if left.val.(bool) == (ins.op == opJumpIfTrue) {
+				// the right operand is skipped, but an operand that can never be a boolean is still a type error
+				if ins.skippedKnown && !ins.skipped.IsBool() {
+					return value{}, ins.node.locate(ins.node.symbol.formatTypeError(left.tp, ins.skipped))
+				}
+				pc = ins.arg - 1
+				continue
+			}
+			stack = stack[:top]
+		case opMakeList:
+			items := stack[len(stack)-ins.arg:]
+			list := make([]interface{}, len(items))
+			for i, item := range items {
+				list[i] = item.val
+			}
+			stack = append(stack[:len(stack)-ins.arg], value{val: list, tp: TypeList})
+		case opCall:
+			base := len(stack) - ins.arg
+			// the arguments are copied: a view of the stack would escape through the function and move the
+			// stack to the heap for every evaluation, even those without a call
+			args := make([]value, ins.arg)
+			copy(args, stack[base:])
+			ret, err := ins.node.function.call(f, args)
+			if err != nil {
+				return value{}, ins.node.locate(err)
+			}
+			stack = append(stack[:base], ret)
+		case opBranch:
+			top := len(stack) - 1
+			cond := stack[top]
+			if !cond.tp.IsBool() {
+				return value{}, ins.node.locate(fmt.Errorf(condErrFmt, cond.tp.String()))
+			}
+			stack = stack[:top]
+			if !cond.val.(bool) {
+				pc = ins.arg - 1
+			}
+		case opJump:
+			pc = ins.arg - 1
+		case opCoalesce:
+			top := len(stack) - 1
+			if !stack[top].tp.IsNull() {
+				pc = ins.arg - 1
+				continue
+			}
+			stack = stack[:top]
+		case opLogical:
+			right := stack[len(stack)-1]
+			if !right.tp.IsBool() {
+				return value{}, ins.node.locate(ins.node.symbol.formatTypeError(TypeBool, right.tp))
+			}
+		}
+	}
+
+	return stack[len(stack)-1], nil
+}
+
+// fastBinary evaluates the most common binary operations on integers, floats and strings without going through
+// the type checker and the operator of the node. It reports false if the operation is not covered, the result must
+// be identical to the one of the operator.
+func fastBinary(symbol Symbol, left, right value) (value, bool) {
+	switch {
+	case left.tp == TypeInteger && right.tp == TypeInteger:
+		l, r := left.val.(int64), right.val.(int64)
+		switch symbol {
+		// an overflow and a division by zero are left to the operator, which reports them
+		case PLUS:
+			if ret, ok := addInt(l, r); ok {
+				return value{val: ret, tp: TypeInteger}, true
+			}
+		case MINUS:
+			if ret, ok := subInt(l, r); ok {
+				return value{val: ret, tp: TypeInteger}, true
+			}
+		case MULTIPLY:
+			if ret, ok := mulInt(l, r); ok {
+				return value{val: ret, tp: TypeInteger}, true
+			}
+		case DIVIDE:
+			if r == 0 || (l == math.MinInt64 && r == -1) {
+				break
+			}
+			if l%r == 0 {
+				return value{val: l / r, tp: TypeInteger}, true
+			}
+			return value{val: float64(l) / float64(r), tp: TypeFloat}, true
+		case MODULUS:
+			if r != 0 {
+				return value{val: l % r, tp: TypeInteger}, true
+			}
+		case EQ:
+			return value{val: l == r, tp: TypeBool}, true
+		case NEQ:
+			return value{val: l != r, tp: TypeBool}, true
+		case GT:
+			return value{val: l > r, tp: TypeBool}, true
+		case GTE:
+			return value{val: l >= r, tp: TypeBool}, true
+		case LT:
+			return value{val: l < r, tp: TypeBool}, true
+		case LTE:
+			return value{val: l <= r, tp: TypeBool}, true
+		}
+	case isFastNumber(left.tp) && isFastNumber(right.tp):
+		// a float with a float or an integer gives a float, they are compared exactly
+		l, r := int2float(left.val), int2float(right.val)
+		switch symbol {
+		case PLUS:
+			return value{val: l + r, tp: TypeFloat}, true
+		case MINUS:
+			return value{val: l - r, tp: TypeFloat}, true
+		case MULTIPLY:
+			return value{val: l * r, tp: TypeFloat}, true
+		case DIVIDE:
+			if r != 0 {
+				return value{val: l / r, tp: TypeFloat}, true
+			}
+		case MODULUS:
+			if r != 0 {
+				return value{val: math.Mod(l, r), tp: TypeFloat}, true
+			}
+		case EQ, NEQ, GT, GTE, LT, LTE:
+			c, ordered := cmpNumbers(left.val, right.val)
+			return value{val: compared(symbol, c, ordered), tp: TypeBool}, true
+		}
+	case left.tp == TypeString && right.tp == TypeString:
+		l, r := left.val.(string), right.val.(string)
+		switch symbol {
+		case EQ:
+			return value{val: l == r, tp: TypeBool}, true
+		case NEQ:
+			return value{val: l != r, tp: TypeBool}, true
+		}
+	}
+	return value{}, false
+}
+
+func isFastNumber(tp TypeFlags) bool {
+	return tp == TypeInteger || tp == TypeFloat
+}
+
+// fastUnary evaluates `!` on a boolean like fastBinary.
+func fastUnary(symbol Symbol, right value) (value, bool) {
+	if symbol == INVERT && right.tp == TypeBool {
+		return value{val: !right.val.(bool), tp: TypeBool}, true
+	}
+	return value{}, false
+}
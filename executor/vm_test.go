@@ -0,0 +1,100 @@
+package executor_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qimengxingyuan/young_engine/executor"
+)
+
+var vmParams = executor.MapParameters{
+	"a": int64(7), "b": int64(3), "c": 2.5, "zero": int64(0),
+	"s": "abc", "t": "abd", "yes": true, "no": false,
+	"uid": int64(10086), "age": int64(25), "score": 88.5, "city": "sh",
+	"level": int64(3), "vip": true, "banned": false, "balance": 1024.75,
+	"created": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "ttl": 90 * time.Minute, "price": executor.NewDecimal(1999, 2),
+	"ids": []int64{1, 2, 3}, "none": nil, "user": map[string]interface{}{"age": 30, "tags": []string{"a", "b"}},
+}
+
+var vmCorpus = []string{
+	`1`, `-1`, `+2.5`, `!yes`, `!!no`, `(a)`, `((a + b))`,
+	`a + b * c`, `(a + b) * c`, `a - b - 1`, `a / b`, `a % b`, `c / 0.5`, `-a * -b`,
+	`a > b`, `a >= 7`, `c < a`, `c <= 2.5`, `a == 7`, `a != b`, `s == "abc"`, `s != t`, `s + t == "abcabd"`,
+	`s < t`, `yes == no`, `yes != true`,
+	`yes && no`, `yes || no`, `no && missing`, `yes || missing`, `!no && (a > b || missing)`,
+	`zero != 0 && 10 / zero > 1`, `zero == 0 || 10 / zero > 1`,
+	`a / zero`, `missing + 1`, `s + a`, `-s`, `!a`, `yes && a`, `a && yes`, `no && 1`, `yes || 1 + 2`, `yes && 1`,
+	`city in ("bj", "sh")`, `a not in [1, b, a + 1]`, `[a, s, [yes]]`, `s in [a, t]`, `a in s`, `b in ids`,
+	`user.age > 18 && user.tags[1] == "b"`, `user["age"] - ids[2]`, `user.name`, `ids[5]`,
+	`len(s) + max(a, b, c)`, `upper(s) == "ABC" && abs(-a) > b`, `substr(s, a)`, `int(c) * len(ids)`,
+	`s =~ "^ab" && t !~ s`, `city =~ "(" + s`,
+	`vip ? balance * 0.8 : balance`, `no ? 1 : yes ? a : b`, `(yes ? [a] : ids)[0] + 1`, `a ? 1 : 2`, `no ? missing : s`,
+	`missing ?? a`, `none ?? s`, `user.email ?? user.age`, `missing == null && none == null`, `none + 1`, `none > a`,
+	`!none`, `a in none`, `yes || none`,
+	`a & 3 != 0`, `a | b ^ 1`, `~a & 255`, `1 << b >> 1`, `a << -1`, `a & c`, `~c`, `-a ** 2`, `a ** b ** 2`, `a ** -1`,
+	`c ** 2`, `none & 1`,
+	`created + 7d > @2024-01-05`, `created - @2023-12-31`, `ttl * 2 + 1m`, `ttl / 30m`, `-ttl`, `hour(created + ttl)`,
+	`created + created`, `ttl > created`,
+	`price * 3 - 0.1`, `price / b`, `price % 5 > 4`, `price ** 2`, `-price`, `round(price / 3, 2)`, `price in [19.99]`,
+	`price ** c`, `price / zero`,
+	`a == 7.0`, `c != 2`, `a in [7.0]`, `uid * 1000000000000000`, `-uid - 9223372036854775807`, `a % zero`, `c % 2`,
+	`age >= 18 && city == "sh" && !banned && (vip || score > 90) && balance - 100 > 500`,
+	`uid % 10 == 6 && level * 10 + age > 50 || (score / 2 > 40 && city != "bj") || missing`,
+}
+
+func TestProgram_EvalMatchesTreeWalk(t *testing.T) {
+	for _, exp := range vmCorpus {
+		program := compile(t, exp)
+
+		wantVal, wantTp, wantErr := program.Root().Eval(vmParams)
+		gotVal, gotTp, gotErr := program.Eval(vmParams)
+		if fmt.Sprint(wantErr) != fmt.Sprint(gotErr) {
+			t.Errorf("%q: vm error %v, tree walk error %v", exp, gotErr, wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(wantVal, gotVal) || wantTp != gotTp {
+			t.Errorf("%q: vm got %v (%v), tree walk got %v (%v)\n%s",
+				exp, gotVal, gotTp, wantVal, wantTp, program.Disassemble())
+		}
+	}
+}
+
+// TestProgram_EvalAllocs checks that the stack of the vm does not move to the heap, the vm allocates no more than
+// the tree walk.
+func TestProgram_EvalAllocs(t *testing.T) {
+	program := compile(t, benchRule)
+	vm := testing.AllocsPerRun(100, func() { _, _, _ = program.Eval(vmParams) })
+	walk := testing.AllocsPerRun(100, func() { _, _, _ = program.Root().Eval(vmParams) })
+	if vm > walk {
+		t.Errorf("the vm allocates %v times per evaluation, the tree walk %v times", vm, walk)
+	}
+}
+
+// a typical rule of about 40 nodes
+const benchRule = `age >= 18 && city == "sh" && !banned && (vip || score > 90) && balance - 100 > 500 && ` +
+	`(uid % 10 == 6 || level * 10 + age > 50) && (score / 2 > 40 || city != "bj")`
+
+func BenchmarkNode_Eval(b *testing.B) {
+	program := compile(b, benchRule)
+	root := program.Root()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := root.Eval(vmParams); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgram_Eval(b *testing.B) {
+	program := compile(b, benchRule)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := program.Eval(vmParams); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package grammar
+
+import (
+	"fmt"
+	"sort"
+)
+
+// set is a set of terminals.
+type set map[string]bool
+
+func (s set) addAll(other set) bool {
+	changed := false
+	for t := range other {
+		if !s[t] {
+			s[t], changed = true, true
+		}
+	}
+	return changed
+}
+
+// boundaries are the terminals which may begin and end the sentences of an element, and whether it may be empty.
+type boundaries struct {
+	nullable    bool
+	first, last set
+}
+
+// derivation computes the boundaries of the parser rules, which are recursive, up to a fixed point.
+type derivation struct {
+	rules map[string]*boundaries
+}
+
+func newDerivation(g *Grammar) *derivation {
+	d := &derivation{rules: make(map[string]*boundaries)}
+	for _, name := range g.order {
+		d.rules[name] = &boundaries{first: make(set), last: make(set)}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.order {
+			b, rule := d.boundaries(g.rules[name]), d.rules[name]
+			if b.nullable && !rule.nullable {
+				rule.nullable, changed = true, true
+			}
+			if rule.first.addAll(b.first) {
+				changed = true
+			}
+			if rule.last.addAll(b.last) {
+				changed = true
+			}
+		}
+	}
+	return d
+}
+
+func (d *derivation) boundaries(e *element) boundaries {
+	b := boundaries{first: make(set), last: make(set)}
+	switch e.kind {
+	case terminal:
+		b.first[e.name], b.last[e.name] = true, true
+	case reference:
+		rule := d.rules[e.name]
+		b.nullable = rule.nullable
+		b.first.addAll(rule.first)
+		b.last.addAll(rule.last)
+	case alternation:
+		for _, item := range e.items {
+			ib := d.boundaries(item)
+			b.nullable = b.nullable || ib.nullable
+			b.first.addAll(ib.first)
+			b.last.addAll(ib.last)
+		}
+	case sequence:
+		b.nullable = true
+		for _, item := range e.items {
+			ib := d.boundaries(item)
+			if b.nullable {
+				b.first.addAll(ib.first)
+			}
+			b.nullable = b.nullable && ib.nullable
+		}
+		for i := len(e.items) - 1; i >= 0; i-- {
+			ib := d.boundaries(e.items[i])
+			b.last.addAll(ib.last)
+			if !ib.nullable {
+				break
+			}
+		}
+	}
+	if e.suffix == '?' || e.suffix == '*' {
+		b.nullable = true
+	}
+	return b
+}
+
+// pairs calls add for each pair of terminals which may be adjacent in the sentences of the element.
+func (d *derivation) pairs(e *element, add func(from, to set)) {
+	for _, item := range e.items {
+		d.pairs(item, add)
+	}
+	if e.kind == sequence {
+		for i, item := range e.items {
+			last := d.boundaries(item).last
+			// the terminals following the item, beyond the items which may be empty
+			for _, next := range e.items[i+1:] {
+				nb := d.boundaries(next)
+				add(last, nb.first)
+				if !nb.nullable {
+					break
+				}
+			}
+		}
+	}
+	if e.suffix == '*' || e.suffix == '+' {
+		b := d.boundaries(e)
+		add(b.last, b.first)
+	}
+}
+
+// Transitions returns the terminals which may follow each terminal in the sentences of the start rule, sorted in
+// the order of the lexer rules. The terminals which may begin a sentence are keyed by the empty string, EOF follows
+// the terminals which may end a sentence.
+func (g *Grammar) Transitions() map[string][]string {
+	d := newDerivation(g)
+	next := map[string]set{"": d.rules[g.Start].first}
+	for _, name := range g.order {
+		d.pairs(g.rules[name], func(from, to set) {
+			for t := range from {
+				if next[t] == nil {
+					next[t] = make(set)
+				}
+				next[t].addAll(to)
+			}
+		})
+	}
+
+	transitions := make(map[string][]string)
+	for t, s := range next {
+		transitions[t] = g.sorted(s)
+	}
+	return transitions
+}
+
+// sorted returns the terminals in the order of the lexer rules, EOF comes last.
+func (g *Grammar) sorted(s set) []string {
+	terminals := make([]string, 0, len(s))
+	for t := range s {
+		terminals = append(terminals, t)
+	}
+	sort.Slice(terminals, func(i, j int) bool {
+		return g.tokenIndex(terminals[i]) < g.tokenIndex(terminals[j])
+	})
+	return terminals
+}
+
+func (g *Grammar) tokenIndex(t string) int {
+	if t == EOF {
+		return len(g.Tokens)
+	}
+	return indexOf(g.Tokens, t)
+}
+
+// Operator is an operator of the grammar with its binding power.
+type Operator struct {
+	Token string
+	// Rule is the parser rule of the operator, Level is its depth in the chain of rules from the start rule
+	Rule  string
+	Level int
+	// Prefix tells an operator before its operand from an operator between its two operands
+	Prefix bool
+	// RightAssoc is set for the infix operators whose right operand is the rule itself or a rule above it, such
+	// as `coalesce: add '??' coalesce`
+	RightAssoc bool
+}
+
+// Operators returns the operators of the chain of rules which starts at the first rule of the start rule, sorted
+// by level. Each rule of the chain has one alternative which is the next rule alone, the other alternatives are
+// either a prefix operator `'-' unary`, a left-associative operator `add '+' mul`, a right-associative operator
+// `add '??' coalesce`, or a postfix operator `post '.' Identifier` which is not returned. The chain stops at the
+// first rule without such an alternative.
+func (g *Grammar) Operators() ([]Operator, error) {
+	start := g.rules[g.Start].items[0].items[0]
+	if start.kind != reference {
+		return nil, fmt.Errorf("rule %s: the first item is not a rule", g.Start)
+	}
+
+	var operators []Operator
+	levels := make(map[string]int)
+	seen := make(map[string]*Operator)
+	for rule, level := start.name, 0; ; level++ {
+		if _, exist := levels[rule]; exist {
+			return nil, fmt.Errorf("rule %s: the chain of rules loops", rule)
+		}
+		levels[rule] = level
+
+		var next []string
+		var alternatives [][]*element
+		for _, alt := range g.rules[rule].items {
+			if len(alt.items) == 1 && alt.items[0].kind == reference && alt.items[0].suffix == 0 {
+				next = append(next, alt.items[0].name)
+				continue
+			}
+			alternatives = append(alternatives, alt.items)
+		}
+		if len(next) != 1 {
+			break
+		}
+
+		for _, items := range alternatives {
+			op, err := operator(items, rule, next[0], levels)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule, err)
+			}
+			if op == nil {
+				continue
+			}
+			op.Rule, op.Level = rule, level
+			key := fmt.Sprintf("%s/%v", op.Token, op.Prefix)
+			if prev := seen[key]; prev != nil {
+				// the alternatives of an operator whose right operand may be one of several rules, such as
+				// `add '??' coalesce | add '??' logNot`
+				if *prev == *op {
+					continue
+				}
+				return nil, fmt.Errorf("rule %s: the operator %s is defined twice", rule, op.Token)
+			}
+			seen[key] = op
+			operators = append(operators, *op)
+		}
+		rule = next[0]
+	}
+	return operators, nil
+}
+
+// operator returns the operator of an alternative of the rule, or nil for a postfix operator.
+func operator(items []*element, rule, next string, levels map[string]int) (*Operator, error) {
+	for _, item := range items {
+		if item.suffix != 0 || (item.kind != terminal && item.kind != reference) {
+			return nil, fmt.Errorf("an operator is a sequence of rules and terminals")
+		}
+	}
+	isRule := func(item *element, name string) bool {
+		return item.kind == reference && item.name == name
+	}
+
+	first, last := items[0], items[len(items)-1]
+	switch {
+	case len(items) == 2 && first.kind == terminal && isRule(last, rule):
+		return &Operator{Token: first.name, Prefix: true}, nil
+	case len(items) >= 3 && isRule(first, next) && items[1].kind == terminal && last.kind == reference:
+		if level, exist := levels[last.name]; exist && level <= levels[rule] {
+			return &Operator{Token: items[1].name, RightAssoc: true}, nil
+		}
+	case len(items) == 3 && isRule(first, rule) && items[1].kind == terminal && isRule(last, next):
+		return &Operator{Token: items[1].name}, nil
+	case isRule(first, rule):
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported alternative")
+}
@@ -0,0 +1,43 @@
+// Command gen generates the lexer states of package token and the binding powers of package compiler from the
+// grammar of the expressions. It is run by go generate in package compiler, whose directory is where the default
+// paths are relative to:
+//
+//	go generate ./compiler
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/qimengxingyuan/young_engine/internal/grammar"
+)
+
+func main() {
+	src := flag.String("grammar", "Engine.g4", "the grammar of the expressions")
+	states := flag.String("states", "../token/lexer_states.go", "the file of the lexer states")
+	precedence := flag.String("precedence", "precedence.go", "the file of the binding powers")
+	flag.Parse()
+
+	b, err := os.ReadFile(*src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g, err := grammar.Parse(string(b))
+	if err != nil {
+		log.Fatalf("%s: %v", *src, err)
+	}
+
+	for file, generate := range map[string]func() ([]byte, error){
+		*states:     g.LexerStates,
+		*precedence: g.Precedence,
+	} {
+		code, err := generate()
+		if err != nil {
+			log.Fatalf("%s: %v", *src, err)
+		}
+		if err = os.WriteFile(file, code, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
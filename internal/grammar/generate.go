@@ -0,0 +1,117 @@
+package grammar
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+)
+
+// Header is the first line of the generated files.
+const Header = "// Code generated by internal/grammar/gen from compiler/Engine.g4; DO NOT EDIT.\n"
+
+// start is the kind of token of the parser before the first token of an expression.
+const start = "Illegal"
+
+// LexerStates generates the lexer states of package token: the kinds of tokens which may follow each kind of
+// token. The terminals of the grammar are named like the kinds of tokens, but EOF which is Eof.
+func (g *Grammar) LexerStates() ([]byte, error) {
+	transitions := g.Transitions()
+	kind := func(t string) string {
+		switch t {
+		case "":
+			return start
+		case EOF:
+			return "Eof"
+		}
+		return t
+	}
+
+	var b bytes.Buffer
+	b.WriteString(Header)
+	b.WriteString(`
+package token
+
+// validLexerStates are the kinds of tokens which may follow each kind of token, they are derived from the pairs of
+// adjacent terminals in the rules of compiler/Engine.g4. Illegal is the state before the first token.
+var validLexerStates = map[Kind]LexerState{
+`)
+	for _, t := range append([]string{""}, g.Tokens...) {
+		next, exist := transitions[t]
+		if !exist {
+			return nil, fmt.Errorf("the token %s is not used by the parser rules", t)
+		}
+		fmt.Fprintf(&b, "%s: {\n", kind(t))
+		if n := len(next); n > 0 && next[n-1] == EOF {
+			b.WriteString("isEOF: true,\n")
+		}
+		b.WriteString("validNextKinds: []Kind{\n")
+		for _, n := range next {
+			fmt.Fprintf(&b, "%s,\n", kind(n))
+		}
+		b.WriteString("},\n},\n")
+	}
+	b.WriteString(`Eof: {
+isEOF: true,
+},
+}
+
+// grammarLiterals are the spellings of the tokens in compiler/Engine.g4, the scanner reads the same ones.
+var grammarLiterals = map[Kind]string{
+`)
+	literals := make([]string, 0, len(g.Literals))
+	for literal := range g.Literals {
+		literals = append(literals, literal)
+	}
+	sort.Slice(literals, func(i, j int) bool {
+		return g.tokenIndex(g.Literals[literals[i]]) < g.tokenIndex(g.Literals[literals[j]])
+	})
+	for _, literal := range literals {
+		fmt.Fprintf(&b, "%s: %s,\n", g.Literals[literal], strconv.Quote(literal))
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// Precedence generates the binding powers of the operators of package compiler, which are their levels in the
+// grammar.
+func (g *Grammar) Precedence() ([]byte, error) {
+	operators, err := g.Operators()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(Header)
+	b.WriteString(`
+package compiler
+
+import "github.com/qimengxingyuan/young_engine/token"
+
+// The binding powers of the operators are the levels of their rules in Engine.g4, from 0 for cond: each rule binds
+// more tightly than the rule which refers to it.
+var (
+	infixOperators = map[token.Kind]infixOperator{
+`)
+	for _, op := range operators {
+		if !op.Prefix {
+			assoc := ""
+			if op.RightAssoc {
+				assoc = ", rightAssoc: true"
+			}
+			fmt.Fprintf(&b, "token.%s: {power: %d%s}, // %s\n", op.Token, op.Level, assoc, op.Rule)
+		}
+	}
+	b.WriteString(`}
+
+	prefixOperators = map[token.Kind]bindingPower{
+`)
+	for _, op := range operators {
+		if op.Prefix {
+			fmt.Fprintf(&b, "token.%s: %d, // %s\n", op.Token, op.Level, op.Rule)
+		}
+	}
+	b.WriteString("}\n)\n")
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,273 @@
+// Package grammar reads compiler/Engine.g4, the grammar of the expressions, and derives from it the transitions
+// between the kinds of tokens checked by the parser and the binding powers of the operators of the builder.
+package grammar
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// EOF is the terminal of the end of the expression in the grammar.
+const EOF = "EOF"
+
+// Grammar is an ANTLR 4 grammar restricted to what Engine.g4 needs: parser rules made of rule references, token
+// references and literals, grouped with parenthesis and repeated with '?', '*' and '+'. Only the lexer rules which
+// are a single literal, such as `Addition : '+';`, are read, they name the literals of the parser rules.
+type Grammar struct {
+	// Start is the first parser rule
+	Start string
+	// Tokens are the names of the lexer rules in their order, but the fragments and the skipped rules
+	Tokens []string
+	// Literals are the names of the lexer rules which are a single literal, by literal
+	Literals map[string]string
+
+	rules map[string]*element
+	order []string
+}
+
+type elementKind int
+
+const (
+	terminal elementKind = iota
+	reference
+	sequence
+	alternation
+)
+
+// element is a node of the body of a parser rule. The terminals are named by their lexer rule.
+type element struct {
+	kind  elementKind
+	name  string
+	items []*element
+	// suffix is '?', '*' or '+' for a repeated element, 0 otherwise
+	suffix byte
+}
+
+// Parse reads the rules of the grammar.
+func Parse(src string) (*Grammar, error) {
+	items, err := split(src)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Grammar{Literals: make(map[string]string), rules: make(map[string]*element)}
+	bodies := make(map[string][]string)
+	for len(items) > 0 {
+		end := indexOf(items, ";")
+		if end < 0 {
+			return nil, fmt.Errorf("missing ';' after %q", strings.Join(items, " "))
+		}
+		rule := items[:end]
+		items = items[end+1:]
+
+		switch {
+		case rule[0] == "grammar" || rule[0] == "fragment":
+			continue
+		case len(rule) < 3 || rule[1] != ":":
+			return nil, fmt.Errorf("malformed rule %q", strings.Join(rule, " "))
+		case isToken(rule[0]):
+			if indexOf(rule, "->") >= 0 {
+				// the comments and the spaces
+				continue
+			}
+			g.Tokens = append(g.Tokens, rule[0])
+			if len(rule) == 3 && isLiteral(rule[2]) {
+				g.Literals[unquote(rule[2])] = rule[0]
+			}
+		default:
+			if g.Start == "" {
+				g.Start = rule[0]
+			}
+			g.order = append(g.order, rule[0])
+			bodies[rule[0]] = rule[2:]
+		}
+	}
+	if g.Start == "" {
+		return nil, fmt.Errorf("no parser rule")
+	}
+
+	for _, name := range g.order {
+		p := &ruleParser{grammar: g, items: bodies[name]}
+		body, err := p.alternation()
+		if err == nil && len(p.items) > 0 {
+			err = fmt.Errorf("unexpected %q", p.items[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		g.rules[name] = body
+	}
+	return g, nil
+}
+
+// split splits the source into identifiers, literals and punctuations, the comments and the character sets of the
+// lexer rules are dropped.
+func split(src string) ([]string, error) {
+	var items []string
+	for i := 0; i < len(src); {
+		ch := rune(src[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("comment not terminated")
+			}
+			i += end + 4
+		case ch == '\'' || ch == '[':
+			closing := byte('\'')
+			if ch == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for ; j < len(src) && src[j] != closing; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("%c not terminated", ch)
+			}
+			if ch == '\'' {
+				items = append(items, src[i:j+1])
+			}
+			i = j + 1
+		case ch == '_' || unicode.IsLetter(ch):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			items = append(items, src[i:j])
+			i = j
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], ".."):
+			items = append(items, src[i:i+2])
+			i += 2
+		default:
+			items = append(items, src[i:i+1])
+			i++
+		}
+	}
+	return items, nil
+}
+
+func indexOf(items []string, item string) int {
+	for i, it := range items {
+		if it == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func isToken(name string) bool {
+	return unicode.IsUpper(rune(name[0]))
+}
+
+func isLiteral(item string) bool {
+	return item[0] == '\''
+}
+
+// unquote returns the text of a literal, such as `+` for `'+'`.
+func unquote(literal string) string {
+	var b strings.Builder
+	for i := 1; i < len(literal)-1; i++ {
+		if literal[i] == '\\' {
+			i++
+		}
+		b.WriteByte(literal[i])
+	}
+	return b.String()
+}
+
+// ruleParser parses the body of a parser rule.
+type ruleParser struct {
+	grammar *Grammar
+	items   []string
+}
+
+func (p *ruleParser) peek() string {
+	if len(p.items) == 0 {
+		return ""
+	}
+	return p.items[0]
+}
+
+func (p *ruleParser) next() string {
+	item := p.peek()
+	if len(p.items) > 0 {
+		p.items = p.items[1:]
+	}
+	return item
+}
+
+func (p *ruleParser) alternation() (*element, error) {
+	alt := &element{kind: alternation}
+	for {
+		seq, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		alt.items = append(alt.items, seq)
+		if p.peek() != "|" {
+			return alt, nil
+		}
+		p.next()
+	}
+}
+
+func (p *ruleParser) sequence() (*element, error) {
+	seq := &element{kind: sequence}
+	for {
+		var item *element
+		switch it := p.peek(); {
+		case it == "" || it == "|" || it == ")":
+			if len(seq.items) == 0 {
+				return nil, fmt.Errorf("empty alternative")
+			}
+			return seq, nil
+		case it == "(":
+			p.next()
+			group, err := p.alternation()
+			if err != nil {
+				return nil, err
+			}
+			if p.next() != ")" {
+				return nil, fmt.Errorf("unbalanced parenthesis")
+			}
+			item = group
+		case isLiteral(it):
+			name, exist := p.grammar.Literals[unquote(it)]
+			if !exist {
+				return nil, fmt.Errorf("no lexer rule for the literal %s", it)
+			}
+			p.next()
+			item = &element{kind: terminal, name: name}
+		case isToken(it):
+			if it != EOF && indexOf(p.grammar.Tokens, it) < 0 {
+				return nil, fmt.Errorf("unknown token %s", it)
+			}
+			p.next()
+			item = &element{kind: terminal, name: it}
+		case unicode.IsLetter(rune(it[0])):
+			if indexOf(p.grammar.order, it) < 0 {
+				return nil, fmt.Errorf("unknown rule %s", it)
+			}
+			p.next()
+			item = &element{kind: reference, name: it}
+		default:
+			return nil, fmt.Errorf("unexpected %q", it)
+		}
+
+		if s := p.peek(); s == "?" || s == "*" || s == "+" {
+			p.next()
+			item.suffix = s[0]
+		}
+		seq.items = append(seq.items, item)
+	}
+}
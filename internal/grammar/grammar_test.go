@@ -0,0 +1,109 @@
+package grammar
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestGenerated fails when Engine.g4 and the generated files drift apart, they are generated again by
+// `go generate ./compiler`.
+func TestGenerated(t *testing.T) {
+	src, err := os.ReadFile("../../compiler/Engine.g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, generate := range map[string]func() ([]byte, error){
+		"../../token/lexer_states.go":  g.LexerStates,
+		"../../compiler/precedence.go": g.Precedence,
+	} {
+		want, err := generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is not generated from Engine.g4, run go generate ./compiler", file)
+		}
+	}
+}
+
+func TestGrammar_Transitions(t *testing.T) {
+	g, err := Parse(`
+grammar Test;
+// a call with optional arguments
+expr: Id ('(' (Id (',' Id)*)? ')')? EOF;
+Id: [a-z]+;
+Open: '(';
+Close: ')';
+Comma: ',';
+Whitespace: [ ]+ -> skip;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"":      {"Id"},
+		"Id":    {"Open", "Close", "Comma", EOF},
+		"Open":  {"Id", "Close"},
+		"Close": {EOF},
+		"Comma": {"Id"},
+	}
+	if got := g.Transitions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGrammar_Operators(t *testing.T) {
+	g, err := Parse(`
+expr: or EOF;
+or: or '||' not | not;
+not: '!' not | sum;
+sum: sum '+' pow | pow;
+pow: pri '**' not | pri '**' pow | pri;
+pri: Id | '(' or ')';
+Or: '||'; Not: '!'; Plus: '+'; Pow: '**'; Open: '('; Close: ')'; Id: [a-z]+;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Operator{
+		{Token: "Or", Rule: "or", Level: 0},
+		{Token: "Not", Rule: "not", Level: 1, Prefix: true},
+		{Token: "Plus", Rule: "sum", Level: 2},
+		{Token: "Pow", Rule: "pow", Level: 3, RightAssoc: true},
+	}
+	if got, err := g.Operators(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v %v, want %v", got, err, want)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{src: `expr: a '+' EOF; A: 'a';`, err: "rule expr: unknown rule a"},
+		{src: `expr: A '-' EOF; A: 'a';`, err: "rule expr: no lexer rule for the literal '-'"},
+		{src: `expr: (A EOF; A: 'a';`, err: "rule expr: unbalanced parenthesis"},
+		{src: `expr: A EOF`, err: `missing ';' after "expr : A EOF"`},
+		{src: `A: 'a';`, err: "no parser rule"},
+	}
+
+	for _, c := range cases {
+		if _, err := Parse(c.src); err == nil || err.Error() != c.err {
+			t.Errorf("%q: got error %v, want %q", c.src, err, c.err)
+		}
+	}
+}
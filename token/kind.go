@@ -19,11 +19,14 @@ const (
 	/*
 	* literals of bool, int, string and variables
 	* */
-	Identifier     // variables
-	BoolLiteral    // true, false
-	IntegerLiteral // 12345
-	FloatLiteral   // 123.45
-	StringLiteral  // "abc"
+	Identifier      // variables
+	BoolLiteral     // true, false
+	IntegerLiteral  // 12345
+	FloatLiteral    // 123.45
+	StringLiteral   // "abc"
+	NullLiteral     // null
+	TimeLiteral     // @2024-01-01T00:00:00Z
+	DurationLiteral // 7d
 	//literal_end
 
 	//operator_beg
@@ -31,8 +34,14 @@ const (
 	/*
 	* single character operator
 	* */
-	OpenParen  // (
-	CloseParen // )
+	OpenParen    // (
+	CloseParen   // )
+	OpenBracket  // [
+	CloseBracket // ]
+	Comma        // ,
+	Dot          // .
+	Question     // ?
+	Colon        // :
 
 	/*
 	* arithmetic operator
@@ -42,6 +51,17 @@ const (
 	Multiply    // *
 	Divide      // /
 	Modulus     // %
+	Power       // **
+
+	/*
+	* bitwise operator
+	* */
+	BitAnd     // &
+	BitOr      // |
+	BitXor     // ^
+	BitNot     // ~
+	ShiftLeft  // <<
+	ShiftRight // >>
 
 	/*
 	* cmp operator
@@ -52,6 +72,10 @@ const (
 	LessEqual    // <=
 	Equal        // ==
 	NotEqual     // !=
+	In           // in
+	NotIn        // not in
+	Match        // =~
+	NotMatch     // !~
 
 	/*
 	* logic operator
@@ -60,6 +84,11 @@ const (
 	Or  // ||
 	Not // !
 
+	/*
+	* null coalescing operator
+	* */
+	Coalesce // ??
+
 	//operator_end
 
 	KindEnd
@@ -75,17 +104,26 @@ var tokens = [...]string{
 	/*
 	* literals of nil, bool, number, string
 	* */
-	Identifier:     "Identifier",
-	BoolLiteral:    "BoolLiteral",
-	IntegerLiteral: "IntegerLiteral",
-	FloatLiteral:   "FloatLiteral",
-	StringLiteral:  "StringLiteral",
+	Identifier:      "Identifier",
+	BoolLiteral:     "BoolLiteral",
+	IntegerLiteral:  "IntegerLiteral",
+	FloatLiteral:    "FloatLiteral",
+	StringLiteral:   "StringLiteral",
+	NullLiteral:     "NullLiteral",
+	TimeLiteral:     "TimeLiteral",
+	DurationLiteral: "DurationLiteral",
 
 	/*
 	* single character operator
 	* */
-	OpenParen:  "(",
-	CloseParen: ")",
+	OpenParen:    "(",
+	CloseParen:   ")",
+	OpenBracket:  "[",
+	CloseBracket: "]",
+	Comma:        ",",
+	Dot:          ".",
+	Question:     "?",
+	Colon:        ":",
 
 	/*
 	* arithmetic operator
@@ -95,6 +133,17 @@ var tokens = [...]string{
 	Multiply:    "*",
 	Divide:      "/",
 	Modulus:     "%",
+	Power:       "**",
+
+	/*
+	* bitwise operator
+	* */
+	BitAnd:     "&",
+	BitOr:      "|",
+	BitXor:     "^",
+	BitNot:     "~",
+	ShiftLeft:  "<<",
+	ShiftRight: ">>",
 
 	/*
 	* cmp operator
@@ -105,6 +154,10 @@ var tokens = [...]string{
 	LessEqual:    "<=",
 	Equal:        "==",
 	NotEqual:     "!=",
+	In:           "in",
+	NotIn:        "not in",
+	Match:        "=~",
+	NotMatch:     "!~",
 
 	/*
 	* logic operator
@@ -113,18 +166,37 @@ var tokens = [...]string{
 	Or:  "||",
 	Not: "!",
 
+	/*
+	* null coalescing operator
+	* */
+	Coalesce: "??",
+
 	KindEnd: "KindEnd",
 }
 
 var operatorToKind = map[string]Kind{
 	"(": OpenParen,
 	")": CloseParen,
-
-	"+": Addition,
-	"-": Subtraction,
-	"*": Multiply,
-	"/": Divide,
-	"%": Modulus,
+	"[": OpenBracket,
+	"]": CloseBracket,
+	",": Comma,
+	".": Dot,
+	"?": Question,
+	":": Colon,
+
+	"+":  Addition,
+	"-":  Subtraction,
+	"*":  Multiply,
+	"/":  Divide,
+	"%":  Modulus,
+	"**": Power,
+
+	"&":  BitAnd,
+	"|":  BitOr,
+	"^":  BitXor,
+	"~":  BitNot,
+	"<<": ShiftLeft,
+	">>": ShiftRight,
 
 	">":  GreaterThan,
 	"<":  LessThan,
@@ -132,10 +204,14 @@ var operatorToKind = map[string]Kind{
 	"<=": LessEqual,
 	"==": Equal,
 	"!=": NotEqual,
+	"=~": Match,
+	"!~": NotMatch,
 
 	"&&": And,
 	"||": Or,
 	"!":  Not,
+
+	"??": Coalesce,
 }
 
 // String returns the string corresponding to the token tok.
@@ -161,6 +237,11 @@ func (k Kind) IsEof() bool {
 	return k == Eof
 }
 
+// IsOperator reports whether the kind is an operator or a punctuation, such as `+`, `in` or `(`.
+func (k Kind) IsOperator() bool {
+	return OpenParen <= k && k <= Coalesce
+}
+
 func (k Kind) GetLexerState() (LexerState, error) {
 	state, exist := validLexerStates[k]
 	if exist {
@@ -28,3 +28,22 @@ func TestTokenKindStrings(test *testing.T) {
 		kindStrings[kindString] = struct{}{}
 	}
 }
+
+// TestGrammarLiterals checks that the scanner reads the tokens spelled like the grammar.
+func TestGrammarLiterals(t *testing.T) {
+	for kind, literal := range grammarLiterals {
+		if kind.String() != literal && keywords[literal] != kind {
+			t.Errorf("%v is spelled %q by the grammar", kind, literal)
+		}
+		if op, exist := operatorToKind[literal]; exist && op != kind {
+			t.Errorf("%q is scanned as %v, the grammar has %v", literal, op, kind)
+		}
+	}
+
+	// each kind of token has a state
+	for kind := KindBegin + 1; kind < KindEnd; kind++ {
+		if _, err := kind.GetLexerState(); err != nil {
+			t.Error(err)
+		}
+	}
+}
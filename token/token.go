@@ -7,11 +7,20 @@ type Token struct {
 	Kind     Kind
 	Value    interface{}
 	Position int
+	// Line and Column locate the first character of the token, both start at 1. The position and the column are
+	// counted in characters rather than bytes.
+	Line   int
+	Column int
+	// Raw is the source text of the token, such as `'a\tb'` for the string literal whose value is a, a tab and b,
+	// or `0x1F` for the integer 31
+	Raw string
 }
 
 var keywords = map[string]Kind{
 	"true":  BoolLiteral,
 	"false": BoolLiteral,
+	"in":    In,
+	"null":  NullLiteral,
 }
 
 func LookupOperator(op string) Kind {